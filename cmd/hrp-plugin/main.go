@@ -0,0 +1,25 @@
+//go:build funplugin
+
+// Command hrp-plugin is the HttpRunner v4 plugin exposing the gRPC functions
+// of plugin.Functions to testcases. It needs github.com/httprunner/funplugin,
+// which is not a requirement of the module yet, so it is only built with the
+// funplugin tag:
+//
+//	go get github.com/httprunner/funplugin
+//	go build -tags funplugin -o debugtalk.bin ./cmd/hrp-plugin
+//
+// hrp picks up debugtalk.bin next to the testcases.
+package main
+
+import (
+	"github.com/httprunner/funplugin/fungo"
+
+	"github.com/test-instructor/grpc-plugin/plugin"
+)
+
+func main() {
+	for name, fn := range plugin.Functions {
+		fungo.Register(name, fn)
+	}
+	fungo.Serve()
+}
@@ -20,22 +20,41 @@ var listMutex sync.RWMutex
 var r *rand.Rand
 var rMutex sync.Mutex
 var u *grpc.Server
+var initOnce sync.Once
 
-func StartSvc() {
+func initStore() {
 	r = rand.New(rand.NewSource(time.Now().UnixNano()))
 	userMutex = sync.RWMutex{}
 	listMutex = sync.RWMutex{}
 	UserDB = make(UserMap)
 	UserDBName = make(UserMapName)
+	SetTimerTask()
+}
+
+// NewUserSvc returns a gRPC server with only the User service registered,
+// like a server that doesn't expose reflection.
+func NewUserSvc(opt ...grpc.ServerOption) *grpc.Server {
+	initOnce.Do(initStore)
+	s := grpc.NewServer(opt...)
+	user.RegisterUserServer(s, &UserServerGRPC{})
+	return s
+}
+
+// NewSvc returns a gRPC server with the User service and reflection
+// registered, so it can be served on any listener (e.g. with TLS options).
+func NewSvc(opt ...grpc.ServerOption) *grpc.Server {
+	s := NewUserSvc(opt...)
+	// Register reflection service on gRPC server.
+	reflection.Register(s)
+	return s
+}
+
+func StartSvc() {
 	lis, err := net.Listen("tcp", port)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
-	SetTimerTask()
-	u = grpc.NewServer()
-	user.RegisterUserServer(u, &UserServerGRPC{})
-	// Register reflection service on gRPC server.
-	reflection.Register(u)
+	u = NewSvc()
 	if err := u.Serve(lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
 	}
@@ -26,8 +26,10 @@ func (t *timer) AddTaskByFunc(taskName string, task func()) (cron.EntryID, error
 
 func SetUserW() {
 	userMutex.Lock()
-	listLen := uint32(len(UserList))
 	defer userMutex.Unlock()
+	listMutex.Lock()
+	defer listMutex.Unlock()
+	listLen := uint32(len(UserList))
 
 	for i := listLen + 1; i < UserID; i++ {
 		ue := UserDB[uint32(i)]
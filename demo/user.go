@@ -8,6 +8,7 @@ import (
 	"github.com/test-instructor/grpc-plugin/demo/user"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"io"
 	"os"
 	"sort"
 	"strconv"
@@ -76,7 +77,6 @@ func (u *UserServerGRPC) UploadImg(ctx context.Context, req *user.UploadImgReq)
 		err := errors.New("未登录")
 		return nil, err
 	}
-	return nil, nil
 }
 
 func (u *UserServerGRPC) Cancellation(ctx context.Context, req *user.CancellationReq) (*user.CancellationResp, error) {
@@ -85,8 +85,9 @@ func (u *UserServerGRPC) Cancellation(ctx context.Context, req *user.Cancellatio
 }
 
 func (u *UserServerGRPC) GetUserList(ctx context.Context, req *user.GetUserListReq) (*user.GetUserListResp, error) {
-	listMutex.RLock()
-	defer listMutex.RUnlock()
+	// sorting modifies the list
+	listMutex.Lock()
+	defer listMutex.Unlock()
 	if req.Sort == user.UserListSort_ASC {
 		sort.Sort(UserList)
 	} else {
@@ -110,6 +111,8 @@ func (u *UserServerGRPC) UserInfo(ctx context.Context, req *user.UserInfoReq) (*
 	}
 	token := md.Get("Token")[0]
 	id, _ := strconv.Atoi(md.Get("id")[0])
+	userMutex.RLock()
+	defer userMutex.RUnlock()
 	if UserDB[uint32(id)] == nil {
 		err := errors.New("未登录")
 		return nil, err
@@ -123,7 +126,6 @@ func (u *UserServerGRPC) UserInfo(ctx context.Context, req *user.UserInfoReq) (*
 		err := errors.New("未登录")
 		return nil, err
 	}
-	return nil, nil
 }
 
 func (u *UserServerGRPC) Login(ctx context.Context, req *user.LoginReq) (*user.LoginResp, error) {
@@ -163,9 +165,11 @@ func (u *UserServerGRPC) RegisterUser(ctx context.Context, req *user.RegisterUse
 	fmt.Println("=========================RegisterUser")
 	md, _ := metadata.FromIncomingContext(ctx)
 	fmt.Println(md)
-	userID := UserDBName[req.UserName]
 	header := metadata.New(map[string]string{"Access-Control-Allow-Headers": "X-Requested-With,content-type,Accept,Authorization", "UserName": req.UserName})
 	grpc.SendHeader(ctx, header)
+	userMutex.Lock()
+	defer userMutex.Unlock()
+	userID := UserDBName[req.UserName]
 	if userID == 0 {
 		uid := UserDB.NexUserID()
 		us := &User{}
@@ -174,10 +178,8 @@ func (u *UserServerGRPC) RegisterUser(ctx context.Context, req *user.RegisterUse
 		us.P = req.Pwd
 		us.Sex = req.Sex
 		us.RegisterTime = time.Now()
-		userMutex.Lock()
 		UserDB[uid] = us
 		UserDBName[us.UserName] = us.ID
-		userMutex.Unlock()
 
 		var resp user.RegisterUserResp
 		resp.UserName = req.UserName
@@ -190,3 +192,60 @@ func (u *UserServerGRPC) RegisterUser(ctx context.Context, req *user.RegisterUse
 	}
 
 }
+
+func (u *UserServerGRPC) WatchUsers(req *user.WatchUsersReq, stream user.User_WatchUsersServer) error {
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	ticker := time.NewTicker(interval + time.Millisecond)
+	defer ticker.Stop()
+	for seq := uint32(1); req.Count == 0 || seq <= req.Count; seq++ {
+		resp := user.WatchUsersResp{Seq: seq}
+		userMutex.RLock()
+		if us, ok := UserDB[seq]; ok {
+			resp.ID = us.ID
+			resp.UserName = us.UserName
+		}
+		userMutex.RUnlock()
+		if err := stream.Send(&resp); err != nil {
+			return err
+		}
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func (u *UserServerGRPC) RegisterUsers(stream user.User_RegisterUsersServer) error {
+	var resp user.RegisterUsersResp
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&resp)
+		}
+		if err != nil {
+			return err
+		}
+		us, err := u.RegisterUser(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		resp.Users = append(resp.Users, us)
+	}
+}
+
+func (u *UserServerGRPC) Chat(stream user.User_ChatServer) error {
+	for seq := uint32(1); ; seq++ {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&user.ChatResp{Seq: seq, Text: req.Text}); err != nil {
+			return err
+		}
+	}
+}
@@ -28,6 +28,14 @@ type UserClient interface {
 	UploadImg(ctx context.Context, in *UploadImgReq, opts ...grpc.CallOption) (*UploadImgResp, error)
 	GetUserList(ctx context.Context, in *GetUserListReq, opts ...grpc.CallOption) (*GetUserListResp, error)
 	UserInfo(ctx context.Context, in *UserInfoReq, opts ...grpc.CallOption) (*UserInfoResp, error)
+	// WatchUsers streams a message every IntervalMs milliseconds, Count
+	// messages in total or until the client cancels when Count is 0.
+	WatchUsers(ctx context.Context, in *WatchUsersReq, opts ...grpc.CallOption) (User_WatchUsersClient, error)
+	// RegisterUsers registers every streamed user and replies once the
+	// client closes the stream.
+	RegisterUsers(ctx context.Context, opts ...grpc.CallOption) (User_RegisterUsersClient, error)
+	// Chat replies to every message with its sequence number and text.
+	Chat(ctx context.Context, opts ...grpc.CallOption) (User_ChatClient, error)
 }
 
 type userClient struct {
@@ -92,6 +100,103 @@ func (c *userClient) UserInfo(ctx context.Context, in *UserInfoReq, opts ...grpc
 	return out, nil
 }
 
+func (c *userClient) WatchUsers(ctx context.Context, in *WatchUsersReq, opts ...grpc.CallOption) (User_WatchUsersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &User_ServiceDesc.Streams[0], "/user.User/WatchUsers", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &userWatchUsersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type User_WatchUsersClient interface {
+	Recv() (*WatchUsersResp, error)
+	grpc.ClientStream
+}
+
+type userWatchUsersClient struct {
+	grpc.ClientStream
+}
+
+func (x *userWatchUsersClient) Recv() (*WatchUsersResp, error) {
+	m := new(WatchUsersResp)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *userClient) RegisterUsers(ctx context.Context, opts ...grpc.CallOption) (User_RegisterUsersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &User_ServiceDesc.Streams[1], "/user.User/RegisterUsers", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &userRegisterUsersClient{stream}
+	return x, nil
+}
+
+type User_RegisterUsersClient interface {
+	Send(*RegisterUserReq) error
+	CloseAndRecv() (*RegisterUsersResp, error)
+	grpc.ClientStream
+}
+
+type userRegisterUsersClient struct {
+	grpc.ClientStream
+}
+
+func (x *userRegisterUsersClient) Send(m *RegisterUserReq) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *userRegisterUsersClient) CloseAndRecv() (*RegisterUsersResp, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(RegisterUsersResp)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *userClient) Chat(ctx context.Context, opts ...grpc.CallOption) (User_ChatClient, error) {
+	stream, err := c.cc.NewStream(ctx, &User_ServiceDesc.Streams[2], "/user.User/Chat", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &userChatClient{stream}
+	return x, nil
+}
+
+type User_ChatClient interface {
+	Send(*ChatReq) error
+	Recv() (*ChatResp, error)
+	grpc.ClientStream
+}
+
+type userChatClient struct {
+	grpc.ClientStream
+}
+
+func (x *userChatClient) Send(m *ChatReq) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *userChatClient) Recv() (*ChatResp, error) {
+	m := new(ChatResp)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // UserServer is the server API for User service.
 // All implementations must embed UnimplementedUserServer
 // for forward compatibility
@@ -102,6 +207,14 @@ type UserServer interface {
 	UploadImg(context.Context, *UploadImgReq) (*UploadImgResp, error)
 	GetUserList(context.Context, *GetUserListReq) (*GetUserListResp, error)
 	UserInfo(context.Context, *UserInfoReq) (*UserInfoResp, error)
+	// WatchUsers streams a message every IntervalMs milliseconds, Count
+	// messages in total or until the client cancels when Count is 0.
+	WatchUsers(*WatchUsersReq, User_WatchUsersServer) error
+	// RegisterUsers registers every streamed user and replies once the
+	// client closes the stream.
+	RegisterUsers(User_RegisterUsersServer) error
+	// Chat replies to every message with its sequence number and text.
+	Chat(User_ChatServer) error
 	mustEmbedUnimplementedUserServer()
 }
 
@@ -127,6 +240,15 @@ func (UnimplementedUserServer) GetUserList(context.Context, *GetUserListReq) (*G
 func (UnimplementedUserServer) UserInfo(context.Context, *UserInfoReq) (*UserInfoResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UserInfo not implemented")
 }
+func (UnimplementedUserServer) WatchUsers(*WatchUsersReq, User_WatchUsersServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchUsers not implemented")
+}
+func (UnimplementedUserServer) RegisterUsers(User_RegisterUsersServer) error {
+	return status.Errorf(codes.Unimplemented, "method RegisterUsers not implemented")
+}
+func (UnimplementedUserServer) Chat(User_ChatServer) error {
+	return status.Errorf(codes.Unimplemented, "method Chat not implemented")
+}
 func (UnimplementedUserServer) mustEmbedUnimplementedUserServer() {}
 
 // UnsafeUserServer may be embedded to opt out of forward compatibility for this service.
@@ -248,6 +370,79 @@ func _User_UserInfo_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _User_WatchUsers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchUsersReq)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UserServer).WatchUsers(m, &userWatchUsersServer{stream})
+}
+
+type User_WatchUsersServer interface {
+	Send(*WatchUsersResp) error
+	grpc.ServerStream
+}
+
+type userWatchUsersServer struct {
+	grpc.ServerStream
+}
+
+func (x *userWatchUsersServer) Send(m *WatchUsersResp) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _User_RegisterUsers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(UserServer).RegisterUsers(&userRegisterUsersServer{stream})
+}
+
+type User_RegisterUsersServer interface {
+	SendAndClose(*RegisterUsersResp) error
+	Recv() (*RegisterUserReq, error)
+	grpc.ServerStream
+}
+
+type userRegisterUsersServer struct {
+	grpc.ServerStream
+}
+
+func (x *userRegisterUsersServer) SendAndClose(m *RegisterUsersResp) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *userRegisterUsersServer) Recv() (*RegisterUserReq, error) {
+	m := new(RegisterUserReq)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _User_Chat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(UserServer).Chat(&userChatServer{stream})
+}
+
+type User_ChatServer interface {
+	Send(*ChatResp) error
+	Recv() (*ChatReq, error)
+	grpc.ServerStream
+}
+
+type userChatServer struct {
+	grpc.ServerStream
+}
+
+func (x *userChatServer) Send(m *ChatResp) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *userChatServer) Recv() (*ChatReq, error) {
+	m := new(ChatReq)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // User_ServiceDesc is the grpc.ServiceDesc for User service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -280,6 +475,23 @@ var User_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _User_UserInfo_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchUsers",
+			Handler:       _User_WatchUsers_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RegisterUsers",
+			Handler:       _User_RegisterUsers_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Chat",
+			Handler:       _User_Chat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "user.proto",
 }
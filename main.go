@@ -1,10 +1,18 @@
 package main
 
 import (
+	"os"
+
 	"github.com/test-instructor/grpc-plugin/demo"
+	"github.com/test-instructor/grpc-plugin/plugin"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "ui" {
+		// e.g. go run . ui -host 127.0.0.1:40061 -addr 127.0.0.1:8080
+		plugin.UICommand(os.Args[2:])
+		return
+	}
 	demo.StartSvc()
 	//defer demo.StopSvc()
 	//rand.Seed(time.Now().UnixNano())
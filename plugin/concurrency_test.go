@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentCalls shares one InvokeGrpc between goroutines, run it with
+// -race.
+func TestConcurrentCalls(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r)
+	ctx := context.Background()
+
+	const workers, rounds = 8, 10
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := 0; n < rounds; n++ {
+				name := uniqueName(fmt.Sprintf("%s-%d-%d", t.Name(), w, n))
+				body, _ := json.Marshal(map[string]string{"UserName": name, "Pwd": "pwd"})
+				results, err := ig.Call(ctx, Request{Method: "user.User.RegisterUser", Body: strings.NewReader(string(body))})
+				if err != nil || results.Error != nil || len(results.Responses) != 1 {
+					t.Errorf("RegisterUser failed: %v %+v", err, results)
+					return
+				}
+
+				body, _ = json.Marshal(map[string]string{"UserName": name, "P": "pwd"})
+				results, err = ig.Call(ctx, Request{Method: "user.User.Login", Body: strings.NewReader(string(body))})
+				if err != nil || results.Error != nil || len(results.Responses) != 1 {
+					t.Errorf("Login failed: %v %+v", err, results)
+					return
+				}
+
+				results, err = ig.Call(ctx, Request{Method: "user.User.GetUserList", Body: strings.NewReader("{}")})
+				if err != nil || results.Error != nil {
+					t.Errorf("GetUserList failed: %v %+v", err, results)
+					return
+				}
+
+				results, err = ig.CallStream(ctx, Request{Method: "user.User.WatchUsers", Body: strings.NewReader(`{"Count":2}`)}, StreamOptions{})
+				if err != nil || results.Error != nil || len(results.Responses) != 2 {
+					t.Errorf("WatchUsers failed: %v %+v", err, results)
+					return
+				}
+
+				// lookups must not see a source narrowed by the calls
+				svcs, err := ig.GetSvs()
+				if err != nil || !reflect.DeepEqual(svcs, []string{"user.User"}) {
+					t.Errorf("GetSvs returned %v, %v", svcs, err)
+					return
+				}
+				if _, err := ig.GetReq("user.User", "Login"); err != nil {
+					t.Errorf("GetReq failed: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,156 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	defaultDialTimeout      = 10 * time.Second
+	defaultMaxRecvMsgSize   = 1024 * 1024 * 256
+	defaultMaxReconnects    = 3
+	defaultReconnectBackoff = 100 * time.Millisecond
+	maxReconnectBackoff     = 2 * time.Second
+)
+
+// DialConfig tunes how the connection to Grpc.Host is established. A nil
+// *DialConfig, like any zero field, keeps the defaults: a 10s fail-fast dial,
+// no keepalive, a 256MB max receive size and up to 3 reconnects.
+type DialConfig struct {
+	// Timeout is the dial timeout in seconds.
+	Timeout float32 `json:"timeout"`
+	// KeepaliveTime is the interval in seconds between client keepalive
+	// pings, zero disables keepalive. KeepaliveTimeout is how long to wait
+	// for the ping ack and defaults to KeepaliveTime.
+	KeepaliveTime    float32 `json:"keepalive_time"`
+	KeepaliveTimeout float32 `json:"keepalive_timeout"`
+	// MaxSendMsgSize and MaxRecvMsgSize limit the size of messages in bytes.
+	MaxSendMsgSize int `json:"max_send_msg_size"`
+	MaxRecvMsgSize int `json:"max_recv_msg_size"`
+	// UserAgent is prepended to the grpc-go user agent.
+	UserAgent string `json:"user_agent"`
+	// Authority overrides the :authority pseudo-header, which otherwise
+	// defaults to Grpc.Host.
+	Authority string `json:"authority"`
+	// WaitForReady makes RPCs wait for the connection to become ready
+	// instead of failing immediately while it is reconnecting.
+	WaitForReady bool `json:"wait_for_ready"`
+	// DisableFailFast keeps retrying transient connection errors until the
+	// dial timeout expires, instead of returning the first error.
+	DisableFailFast bool `json:"disable_fail_fast"`
+	// MaxReconnects is how many times a call dials the host again when the
+	// connection turns out to be unavailable, e.g. after a server restart.
+	// A negative value disables reconnecting. ReconnectBackoff is the pause
+	// in seconds before the first reconnect, doubled for each further one.
+	MaxReconnects    int     `json:"max_reconnects"`
+	ReconnectBackoff float32 `json:"reconnect_backoff"`
+	// An RPC is only sent again on a new connection if it failed before
+	// reaching the server. RetryIdempotent also sends again the RPCs that
+	// failed as Unavailable once sent, if their method is marked with an
+	// idempotency_level of IDEMPOTENT or NO_SIDE_EFFECTS.
+	RetryIdempotent bool `json:"retry_idempotent"`
+}
+
+func seconds(s float32) time.Duration {
+	return time.Duration(s * float32(time.Second))
+}
+
+func (c *DialConfig) timeout() time.Duration {
+	if c == nil || c.Timeout <= 0 {
+		return defaultDialTimeout
+	}
+	return seconds(c.Timeout)
+}
+
+func (c *DialConfig) failFast() bool {
+	return c == nil || !c.DisableFailFast
+}
+
+func (c *DialConfig) maxReconnects() int {
+	switch {
+	case c == nil || c.MaxReconnects == 0:
+		return defaultMaxReconnects
+	case c.MaxReconnects < 0:
+		return 0
+	}
+	return c.MaxReconnects
+}
+
+func (c *DialConfig) retryIdempotent() bool {
+	return c != nil && c.RetryIdempotent
+}
+
+func (c *DialConfig) reconnectBackoff() time.Duration {
+	if c == nil || c.ReconnectBackoff <= 0 {
+		return defaultReconnectBackoff
+	}
+	return seconds(c.ReconnectBackoff)
+}
+
+func (c *DialConfig) dialOptions() []grpc.DialOption {
+	var conf DialConfig
+	if c != nil {
+		conf = *c
+	}
+	var opts []grpc.DialOption
+	if conf.KeepaliveTime > 0 {
+		timeout := conf.KeepaliveTimeout
+		if timeout <= 0 {
+			timeout = conf.KeepaliveTime
+		}
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    seconds(conf.KeepaliveTime),
+			Timeout: seconds(timeout),
+		}))
+	}
+
+	maxRecv := conf.MaxRecvMsgSize
+	if maxRecv <= 0 {
+		maxRecv = defaultMaxRecvMsgSize
+	}
+	callOpts := []grpc.CallOption{grpc.MaxCallRecvMsgSize(maxRecv)}
+	if conf.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(conf.MaxSendMsgSize))
+	}
+	if conf.WaitForReady {
+		callOpts = append(callOpts, grpc.WaitForReady(true))
+	}
+	opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+
+	if conf.UserAgent != "" {
+		opts = append(opts, grpc.WithUserAgent(conf.UserAgent))
+	}
+	if conf.Authority != "" {
+		opts = append(opts, grpc.WithAuthority(conf.Authority))
+	}
+	return opts
+}
+
+// connKey identifies a cached connection: requests only share a connection
+// when they target the same host with the same TLS, dial, descriptor and
+// reflection settings.
+func (g *Grpc) connKey() string {
+	var tlsConf TLSConfig
+	if g.TLS != nil {
+		tlsConf = *g.TLS
+	}
+	var dialConf DialConfig
+	if g.Dial != nil {
+		dialConf = *g.Dial
+	}
+	// funcs can't be compared, the provider is told apart by its key or by
+	// the Grpc holding it
+	var provider string
+	switch {
+	case g.ReflectMetadataProvider == nil:
+	case g.ReflectMetadataKey != "":
+		provider = "key:" + g.ReflectMetadataKey
+	default:
+		provider = fmt.Sprintf("grpc:%p", g)
+	}
+	return fmt.Sprintf("%s|%t|%+v|%+v|%q|%q|%q|%t|%+v|%q", g.Host, g.TLS != nil, tlsConf, dialConf,
+		g.ImportPaths, g.ProtoFiles, g.Protosets, g.DisableReflection, g.ReflectMetadata, provider)
+}
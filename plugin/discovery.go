@@ -0,0 +1,215 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The bounds of the registry of a DiscoveryHandler given none.
+const (
+	DiscoveryIdleTTL    = 5 * time.Minute
+	DiscoveryMaxClients = 16
+)
+
+// DiscoveryHandler returns an HTTP handler that exposes the services,
+// methods and schemas of the hosts config allows as JSON:
+//
+//	GET  /hosts/{host}/services                   names of the services
+//	GET  /hosts/{host}/services/{service}/methods names of the methods of a service
+//	GET  /hosts/{host}/methods/{method}/schema    the description of a method, see GetMethodInfo
+//	GET  /hosts/{host}/symbols/{symbol}/describe  the proto source of a symbol, see Resource.Describe
+//	POST /hosts/{host}/reset                      dial the host again, see Registry.Reset
+//
+// Path segments may be escaped, e.g. a method named user.User%2FLogin.
+// config returns the connection settings of a host, or nil to forbid it, so
+// that clients can't make the server connect anywhere; nil config forbids
+// every host, see AllowHosts. Hosts are connected to through registry, or if
+// nil through a registry of the handler closing clients idle for
+// DiscoveryIdleTTL and keeping at most DiscoveryMaxClients.
+//
+// Failures are answered with a status code matching the kind of the error,
+// e.g. 404 for ErrMethodNotFound and 502 for ErrDial, and a JSON body
+// holding the error. The message is localized when a lang query parameter is
+// given, see Localize. The handler doesn't authenticate its clients, wrap it
+// to restrict who may reset connections.
+func DiscoveryHandler(registry *Registry, config func(host string) *Grpc) http.Handler {
+	if registry == nil {
+		registry = NewRegistry(DiscoveryIdleTTL, DiscoveryMaxClients)
+	}
+	if config == nil {
+		config = AllowHosts()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		segments, err := pathSegments(r.URL.EscapedPath())
+		if err != nil || len(segments) < 3 || segments[0] != "hosts" {
+			http.NotFound(w, r)
+			return
+		}
+		host, route := segments[1], segments[2:]
+		g := config(host)
+		if g == nil {
+			writeDiscoveryError(w, r, http.StatusForbidden, errors.New("host "+host+" is not allowed"))
+			return
+		}
+		i := NewInvokeGrpcWithRegistry(g, registry)
+		ctx := r.Context()
+
+		var handle func(ctx context.Context) (interface{}, error)
+		method := "GET"
+		switch {
+		case len(route) == 1 && route[0] == "services":
+			handle = func(ctx context.Context) (interface{}, error) {
+				svcs, err := i.GetSvsContext(ctx)
+				return nonNil(svcs), err
+			}
+		case len(route) == 3 && route[0] == "services" && route[2] == "methods":
+			handle = func(ctx context.Context) (interface{}, error) {
+				methods, err := i.GetMethodContext(ctx, route[1])
+				return nonNil(methods), err
+			}
+		case len(route) == 3 && route[0] == "methods" && route[2] == "schema":
+			handle = func(ctx context.Context) (interface{}, error) {
+				return i.GetMethodInfoContext(ctx, route[1])
+			}
+		case len(route) == 3 && route[0] == "symbols" && route[2] == "describe":
+			handle = func(ctx context.Context) (interface{}, error) {
+				return i.describe(ctx, route[1])
+			}
+		case len(route) == 1 && route[0] == "reset":
+			method = "POST"
+			handle = func(ctx context.Context) (interface{}, error) {
+				if _, err := registry.Reset(g); err != nil {
+					return nil, err
+				}
+				return map[string]string{"host": host}, nil
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		results, err := handle(ctx)
+		if err != nil {
+			writeDiscoveryError(w, r, errorStatus(ctx, err), err)
+			return
+		}
+		writeJSON(w, results)
+	})
+}
+
+// AllowHosts returns a config of DiscoveryHandler allowing hosts only, with
+// the default settings.
+func AllowHosts(hosts ...string) func(host string) *Grpc {
+	allowed := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		allowed[host] = true
+	}
+	return func(host string) *Grpc {
+		if !allowed[host] {
+			return nil
+		}
+		return &Grpc{Host: host}
+	}
+}
+
+// pathSegments splits an escaped path into its unescaped segments.
+func pathSegments(path string) ([]string, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for n, part := range parts {
+		var err error
+		if parts[n], err = url.PathUnescape(part); err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+// nonNil makes empty lists encode as [] rather than null.
+func nonNil(names []string) []string {
+	if names == nil {
+		return []string{}
+	}
+	return names
+}
+
+// symbolDescription is the answer of the describe endpoint.
+type symbolDescription struct {
+	Symbol string `json:"symbol"`
+	// Text is the proto source of the symbol.
+	Text string `json:"text"`
+	// Template is a JSON template of the symbol if it is a message.
+	Template string `json:"template,omitempty"`
+}
+
+func (i *InvokeGrpc) describe(ctx context.Context, symbol string) (*symbolDescription, error) {
+	d, source, err := i.findSymbol(ctx, strings.TrimPrefix(symbol, "."))
+	if err != nil {
+		return nil, err
+	}
+	txt, template, err := describeDescriptor(d, source)
+	if err != nil {
+		return nil, err
+	}
+	return &symbolDescription{Symbol: d.GetFullyQualifiedName(), Text: txt, Template: template}, nil
+}
+
+// errorStatus maps err to the HTTP status code of its kind.
+func errorStatus(ctx context.Context, err error) int {
+	switch {
+	case errors.Is(err, ErrMethodNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrBadInput):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrDial), errors.Is(err, ErrReflection), errors.Is(err, ErrRPCStatus):
+		return http.StatusBadGateway
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case ctx.Err() != nil:
+		// the client went away, the status is not going to be read anyway
+		return 499
+	}
+	return http.StatusInternalServerError
+}
+
+// discoveryError is the body of the answers of the discovery endpoints that
+// failed.
+type discoveryError struct {
+	Error struct {
+		// Kind is the text of the kind of the error, e.g. "method not
+		// found", if it has one.
+		Kind        string   `json:"kind,omitempty"`
+		Message     string   `json:"message"`
+		Suggestions []string `json:"suggestions,omitempty"`
+	} `json:"error"`
+}
+
+func writeDiscoveryError(w http.ResponseWriter, r *http.Request, code int, err error) {
+	var body discoveryError
+	body.Error.Message = Localize(err, r.URL.Query().Get("lang"))
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind) {
+			body.Error.Kind = kind.Error()
+			break
+		}
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		body.Error.Suggestions = e.Suggestions
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscoveryHandler(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	closed := closedAddr(t)
+	h := DiscoveryHandler(r, AllowHosts(addr, closed))
+
+	do := func(method, path string, v interface{}) int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		if v != nil {
+			if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+				t.Fatalf("%s %s: invalid body %s: %v", method, path, rec.Body, err)
+			}
+		}
+		return rec.Code
+	}
+	base := "/hosts/" + addr
+
+	var svcs []string
+	if code := do("GET", base+"/services", &svcs); code != http.StatusOK || !reflect.DeepEqual(svcs, []string{"user.User"}) {
+		t.Fatalf("unexpected services %d %v", code, svcs)
+	}
+	var methods []string
+	if code := do("GET", base+"/services/user.User/methods", &methods); code != http.StatusOK || len(methods) == 0 || methods[0] != "RegisterUser" {
+		t.Fatalf("unexpected methods %d %v", code, methods)
+	}
+	var info schema
+	if code := do("GET", base+"/methods/user.User%2FLogin/schema", &info); code != http.StatusOK || info.RequestType != "user.LoginReq" {
+		t.Fatalf("unexpected schema %d %+v", code, info)
+	}
+	var described symbolDescription
+	if code := do("GET", base+"/symbols/user.LoginReq/describe", &described); code != http.StatusOK ||
+		!strings.Contains(described.Text, "message LoginReq") || described.Template == "" {
+		t.Fatalf("unexpected description %d %+v", code, described)
+	}
+	var reset map[string]string
+	if code := do("POST", base+"/reset", &reset); code != http.StatusOK || reset["host"] != addr {
+		t.Fatalf("unexpected reset %d %v", code, reset)
+	}
+
+	var failed discoveryError
+	if code := do("GET", base+"/methods/user.User.Logn/schema?lang=zh", &failed); code != http.StatusNotFound ||
+		failed.Error.Kind != "method not found" || !strings.HasPrefix(failed.Error.Message, "未找到") ||
+		len(failed.Error.Suggestions) == 0 || failed.Error.Suggestions[0] != "user.User.Login" {
+		t.Fatalf("unexpected error %d %+v", code, failed)
+	}
+	var notService discoveryError
+	if code := do("GET", base+"/services/user.LoginReq/methods", &notService); code != http.StatusNotFound || notService.Error.Kind != "method not found" {
+		t.Fatalf("expected a message named as a service to be missing, got %d %+v", code, notService)
+	}
+	if code := do("GET", "/hosts/"+closed+"/services", &failed); code != http.StatusBadGateway || failed.Error.Kind != "dial failed" {
+		t.Fatalf("expected an unreachable host to be a bad gateway, got %d %+v", code, failed)
+	}
+	if code := do("GET", base+"/reset", nil); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected GET to be rejected, got %d", code)
+	}
+	if code := do("GET", base+"/unknown", nil); code != http.StatusNotFound {
+		t.Fatalf("expected an unknown endpoint to be missing, got %d", code)
+	}
+
+	if code := do("GET", "/hosts/127.0.0.1:1/services", &failed); code != http.StatusForbidden {
+		t.Fatalf("expected a host not allowed to be forbidden, got %d %+v", code, failed)
+	}
+
+	// without a config, clients can't make the server connect anywhere
+	rec := httptest.NewRecorder()
+	DiscoveryHandler(nil, nil).ServeHTTP(rec, httptest.NewRequest("GET", base+"/services", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected every host to be forbidden, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The kinds of failures of the plugin. Errors returned by InvokeGrpc match
+// one of them with errors.Is, except for the errors of ctx, which are
+// returned as is.
+var (
+	// ErrDial means the host could not be connected to.
+	ErrDial = errors.New("dial failed")
+	// ErrReflection means the descriptors of the host could not be
+	// resolved, through reflection or from proto files.
+	ErrReflection = errors.New("reflection failed")
+	// ErrMethodNotFound means the host has no such service or method.
+	ErrMethodNotFound = errors.New("method not found")
+	// ErrBadInput means the request could not be read or doesn't fit the
+	// request type of the method.
+	ErrBadInput = errors.New("bad input")
+	// ErrRPCStatus means the RPC ended with a status other than OK. It is
+	// matched by the *RpcError of RpcResult.Err.
+	ErrRPCStatus = errors.New("rpc failed")
+)
+
+var errorKinds = []error{ErrDial, ErrReflection, ErrMethodNotFound, ErrBadInput, ErrRPCStatus}
+
+// Translations holds the texts of the kinds of errors by language, for
+// Localize. Add to it to support more languages.
+var Translations = map[string]map[error]string{
+	"zh": {
+		ErrDial:           "连接失败",
+		ErrReflection:     "反射失败",
+		ErrMethodNotFound: "未找到对应的请求方式",
+		ErrBadInput:       "请求参数错误",
+		ErrRPCStatus:      "请求返回错误状态",
+	},
+}
+
+// Error is a failure of the plugin, classified by Kind.
+type Error struct {
+	// Kind is one of ErrDial, ErrReflection, ErrMethodNotFound and
+	// ErrBadInput.
+	Kind error
+	// Subject is what failed, e.g. the host or the method.
+	Subject string
+	// Err is the cause of the failure, if known.
+	Err error
+	// Suggestions are the names Subject may have meant, for
+	// ErrMethodNotFound.
+	Suggestions []string
+}
+
+func (e *Error) Error() string {
+	return e.text(e.Kind.Error())
+}
+
+// Localized is like Error, but with the text of Kind in lang. It falls back
+// to English if there is no translation.
+func (e *Error) Localized(lang string) string {
+	if text, ok := Translations[lang][e.Kind]; ok {
+		return e.text(text)
+	}
+	return e.Error()
+}
+
+func (e *Error) text(kind string) string {
+	parts := []string{kind}
+	if e.Subject != "" {
+		parts = append(parts, e.Subject)
+	}
+	if e.Err != nil {
+		parts = append(parts, e.Err.Error())
+	}
+	text := strings.Join(parts, ": ")
+	if len(e.Suggestions) > 0 {
+		text += " (did you mean " + strings.Join(e.Suggestions, ", ") + "?)"
+	}
+	return text
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Localize returns the message of err in lang, e.g. "zh". Errors of an
+// unknown kind or language get their English message.
+func Localize(err error, lang string) string {
+	var e *Error
+	if errors.As(err, &e) {
+		// keep the context err may have wrapped e in
+		return strings.Replace(err.Error(), e.Error(), e.Localized(lang), 1)
+	}
+	for _, kind := range errorKinds {
+		if text, ok := Translations[lang][kind]; ok && errors.Is(err, kind) {
+			return text + ": " + err.Error()
+		}
+	}
+	return err.Error()
+}
+
+// isNotFound reports whether a descriptor lookup failed because the symbol
+// doesn't exist. grpcurl doesn't export its error for that, so it is told
+// apart by its message.
+func isNotFound(err error) bool {
+	if grpcreflect.IsElementNotFoundError(err) || status.Code(err) == codes.NotFound {
+		return true
+	}
+	msg := err.Error()
+	return strings.HasPrefix(msg, "Symbol not found: ") || strings.HasPrefix(msg, "Service not found: ")
+}
+
+// notService is the error of a lookup of the service name that found d,
+// a symbol of another kind.
+func notService(name string, d desc.Descriptor) error {
+	err := fmt.Errorf("%s should be a service descriptor but instead is a %T", d.GetFullyQualifiedName(), d)
+	return &Error{Kind: ErrMethodNotFound, Subject: name, Err: err}
+}
+
+// lookupError classifies the error of a descriptor lookup of subject as
+// ErrMethodNotFound or ErrReflection. Errors of ctx and errors that are
+// already classified are returned as is.
+func lookupError(ctx context.Context, subject string, err error) error {
+	var e *Error
+	switch {
+	case err == nil, ctx.Err() != nil, errors.As(err, &e), errors.Is(err, ErrReflection):
+		return err
+	case isNotFound(err):
+		return &Error{Kind: ErrMethodNotFound, Subject: subject, Err: err}
+	}
+	return &Error{Kind: ErrReflection, Subject: subject, Err: err}
+}
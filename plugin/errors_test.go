@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// closedAddr returns an address nothing listens on.
+func closedAddr(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+	return addr
+}
+
+func TestErrorKinds(t *testing.T) {
+	addr := startTestSvc(t)
+	auth := &authReflection{token: "secret"}
+	authAddr := startTestSvc(t, grpc.StreamInterceptor(auth.stream))
+
+	tests := []struct {
+		name string
+		g    *Grpc
+		want error
+	}{
+		{"dial", &Grpc{Host: closedAddr(t), Method: "user.User.Login", Dial: &DialConfig{Timeout: 1}}, ErrDial},
+		{"reflection", &Grpc{Host: authAddr, Method: "user.User.Login"}, ErrReflection},
+		{"method not found", &Grpc{Host: addr, Method: "user.User.Missing"}, ErrMethodNotFound},
+		{"bad input", &Grpc{Host: addr, Method: "user.User.Login", Body: strings.NewReader(`{"Unknown":1}`)}, ErrBadInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry(0, 0)
+			defer r.CloseAll()
+			_, err := NewInvokeGrpcWithRegistry(tt.g, r).InvokeFunction()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			for _, kind := range errorKinds {
+				if kind != tt.want && errors.Is(err, kind) {
+					t.Fatalf("%v also matches %v", err, kind)
+				}
+			}
+		})
+	}
+
+	var unauthorized *ReflectionUnauthorizedError
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	_, err := NewInvokeGrpcWithRegistry(&Grpc{Host: authAddr}, r).GetSvs()
+	if !errors.Is(err, ErrReflection) || !errors.As(err, &unauthorized) {
+		t.Fatalf("expected an unauthorized reflection error, got %v", err)
+	}
+	if _, err := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r).GetReq("user.User", "Missing"); !errors.Is(err, ErrMethodNotFound) {
+		t.Fatalf("expected ErrMethodNotFound, got %v", err)
+	}
+	if _, err := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r).GetMethod("user.Missing"); !errors.Is(err, ErrMethodNotFound) {
+		t.Fatalf("expected ErrMethodNotFound, got %v", err)
+	}
+	// a symbol that isn't a service
+	if _, err := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r).GetMethod("user.LoginReq"); !errors.Is(err, ErrMethodNotFound) {
+		t.Fatalf("expected ErrMethodNotFound, got %v", err)
+	}
+	if _, err := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r).GetReq("user.LoginReq", "Login"); !errors.Is(err, ErrMethodNotFound) {
+		t.Fatalf("expected ErrMethodNotFound, got %v", err)
+	}
+}
+
+func TestRPCStatusError(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	g := &Grpc{Host: addr, Method: "user.User.Login", Body: strings.NewReader(fmt.Sprintf(`{"UserName":%q}`, t.Name()))}
+	results, err := NewInvokeGrpcWithRegistry(g, r).InvokeFunction()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = results.Err()
+	var rpcErr *RpcError
+	if !errors.Is(err, ErrRPCStatus) || !errors.As(err, &rpcErr) {
+		t.Fatalf("expected an RPC status error, got %v", err)
+	}
+	if codes.Code(rpcErr.Code) == codes.OK {
+		t.Fatal("expected a failed status")
+	}
+	if got := Localize(err, "zh"); !strings.HasPrefix(got, "请求返回错误状态: ") {
+		t.Fatalf("unexpected localized message %q", got)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	err := &Error{Kind: ErrMethodNotFound, Subject: "user.User.Missing"}
+	if got, want := err.Error(), "method not found: user.User.Missing"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	wrapped := fmt.Errorf("step failed: %w", err)
+	if got, want := Localize(wrapped, "zh"), "step failed: 未找到对应的请求方式: user.User.Missing"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := Localize(wrapped, "fr"); got != wrapped.Error() {
+		t.Fatalf("expected the English message for an unknown language, got %q", got)
+	}
+}
@@ -0,0 +1,153 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Functions are the functions the HttpRunner plugin registers, by the names
+// testcases call them with, e.g. ${grpc_invoke($host, "user.User.Login",
+// $body, $metadata, 3)}. Their results are maps, lists and strings only, so
+// that testcases can extract from them.
+var Functions = map[string]interface{}{
+	"grpc_invoke":           GrpcInvoke,
+	"grpc_list_services":    GrpcListServices,
+	"grpc_list_methods":     GrpcListMethods,
+	"grpc_request_template": GrpcRequestTemplate,
+	"grpc_step":             GrpcRunStep,
+}
+
+// GrpcInvoke calls method on host and returns the result as a map, see
+// ResultMap. body is the request as a JSON string or as any value that
+// marshals to it, such as the maps of a testcase. metadata maps names to
+// values, which are converted to strings. timeout is in seconds, zero means
+// none.
+func GrpcInvoke(host, method string, body interface{}, metadata map[string]interface{}, timeout float64) (map[string]interface{}, error) {
+	req, err := functionBody(body)
+	if err != nil {
+		return nil, err
+	}
+	g := &Grpc{
+		Host:     host,
+		Method:   method,
+		Metadata: functionMetadata(metadata),
+		Timeout:  float32(timeout),
+		Body:     strings.NewReader(req),
+	}
+	results, err := NewInvokeGrpc(g).InvokeFunction()
+	if err != nil {
+		return nil, err
+	}
+	return ResultMap(results)
+}
+
+// GrpcListServices lists the services of host.
+func GrpcListServices(host string) ([]string, error) {
+	return NewInvokeGrpc(&Grpc{Host: host}).GetSvs()
+}
+
+// GrpcListMethods lists the names of the methods of service on host.
+func GrpcListMethods(host, service string) ([]string, error) {
+	return NewInvokeGrpc(&Grpc{Host: host}).GetMethod(service)
+}
+
+// GrpcRequestTemplate returns a request of method on host with every field
+// set to its default value, to be filled in by a testcase.
+func GrpcRequestTemplate(host, method string) (map[string]interface{}, error) {
+	info, err := NewInvokeGrpc(&Grpc{Host: host}).GetMethodInfo(method)
+	if err != nil {
+		return nil, err
+	}
+	var tmpl map[string]interface{}
+	if err := json.Unmarshal([]byte(info.Body), &tmpl); err != nil {
+		return nil, fmt.Errorf("failed to decode the template of %s: %v", method, err)
+	}
+	return tmpl, nil
+}
+
+// ResultMap converts results to plain maps and lists:
+//
+//	code      the status code, 0 for OK
+//	message   the status message
+//	headers   the response headers, by name
+//	trailers  the response trailers, by name
+//	responses the response messages
+//	response  the first response message, handy for unary methods
+//	details   the details of the status
+//
+// Multiple values of a header are joined with ", ".
+func ResultMap(results *RpcResult) (map[string]interface{}, error) {
+	m := map[string]interface{}{
+		"code":     0,
+		"message":  "",
+		"headers":  metadataMap(results.Headers),
+		"trailers": metadataMap(results.Trailers),
+	}
+	responses := make([]interface{}, len(results.Responses))
+	for n, resp := range results.Responses {
+		if err := json.Unmarshal(resp.Data, &responses[n]); err != nil {
+			return nil, fmt.Errorf("failed to decode response %d: %v", n, err)
+		}
+	}
+	m["responses"] = responses
+	if len(responses) > 0 {
+		m["response"] = responses[0]
+	}
+	if results.Error != nil {
+		m["code"] = int(results.Error.Code)
+		m["message"] = results.Error.Message
+		details := make([]interface{}, len(results.Error.Details))
+		for n, detail := range results.Error.Details {
+			if err := json.Unmarshal(detail.Data, &details[n]); err != nil {
+				return nil, fmt.Errorf("failed to decode status detail %d: %v", n, err)
+			}
+		}
+		m["details"] = details
+	}
+	return m, nil
+}
+
+func metadataMap(md []RpcMetadata) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, v := range md {
+		if prev, ok := m[v.Name]; ok {
+			m[v.Name] = prev.(string) + ", " + v.Value
+			continue
+		}
+		m[v.Name] = v.Value
+	}
+	return m
+}
+
+// functionBody returns body as a JSON string.
+func functionBody(body interface{}) (string, error) {
+	switch body := body.(type) {
+	case nil:
+		return "{}", nil
+	case string:
+		return body, nil
+	case []byte:
+		return string(body), nil
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", &Error{Kind: ErrBadInput, Subject: "body", Err: err}
+	}
+	return string(b), nil
+}
+
+// functionMetadata converts metadata to RpcMetadata, sorted by name.
+func functionMetadata(metadata map[string]interface{}) []RpcMetadata {
+	names := make([]string, 0, len(metadata))
+	for name := range metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	md := make([]RpcMetadata, len(names))
+	for n, name := range names {
+		md[n] = RpcMetadata{Name: name, Value: fmt.Sprint(metadata[name])}
+	}
+	return md
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFunctions(t *testing.T) {
+	addr := startTestSvc(t)
+	t.Cleanup(func() { DefaultRegistry.Close(addr) })
+
+	svcs, err := GrpcListServices(addr)
+	if err != nil || !reflect.DeepEqual(svcs, []string{"user.User"}) {
+		t.Fatalf("unexpected services %v %v", svcs, err)
+	}
+	methods, err := GrpcListMethods(addr, "user.User")
+	if err != nil || len(methods) == 0 {
+		t.Fatalf("unexpected methods %v %v", methods, err)
+	}
+	tmpl, err := GrpcRequestTemplate(addr, "Login")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := tmpl["UserName"]; !ok {
+		t.Fatalf("expected a template of LoginReq, got %v", tmpl)
+	}
+
+	// testcases pass bodies as maps and metadata values of any type
+	user := uniqueName("functions")
+	body := map[string]interface{}{"UserName": user, "Pwd": "secret"}
+	result, err := GrpcInvoke(addr, "user.User.RegisterUser", body, map[string]interface{}{"id": 1.0}, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["code"] != 0 || result["headers"].(map[string]interface{})["username"] != user {
+		t.Fatalf("unexpected result %v", result)
+	}
+	if resp := result["response"].(map[string]interface{}); resp["UserName"] != user {
+		t.Fatalf("unexpected response %v", resp)
+	}
+	if _, err := json.Marshal(result); err != nil {
+		t.Fatalf("expected a JSON-friendly result: %v", err)
+	}
+
+	result, err = GrpcInvoke(addr, "Login", `{"UserName":"nobody"}`, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["code"] == 0 || result["message"] == "" || len(result["responses"].([]interface{})) != 0 {
+		t.Fatalf("expected the status of a failed RPC, got %v", result)
+	}
+
+	if _, err := GrpcInvoke(addr, "user.User.Login", map[string]interface{}{"bad": func() {}}, nil, 0); !errors.Is(err, ErrBadInput) {
+		t.Fatalf("expected a body that doesn't marshal to be bad input, got %v", err)
+	}
+}
+
+func TestFunctionMetadata(t *testing.T) {
+	md := functionMetadata(map[string]interface{}{"b": 2.5, "a": "x", "c": true})
+	want := []RpcMetadata{{"a", "x"}, {"b", "2.5"}, {"c", "true"}}
+	if !reflect.DeepEqual(md, want) {
+		t.Fatalf("expected %v, got %v", want, md)
+	}
+}
+
+// TestFunctionsSignatures checks the functions have the shape fungo.Register
+// takes: a func returning a value and optionally an error, and whose
+// arguments testcases can pass.
+func TestFunctionsSignatures(t *testing.T) {
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	for name, fn := range Functions {
+		typ := reflect.TypeOf(fn)
+		if typ.Kind() != reflect.Func {
+			t.Errorf("%s: expected a func, got %v", name, typ)
+			continue
+		}
+		if n := typ.NumOut(); n < 1 || n > 2 || n == 2 && typ.Out(1) != errorType {
+			t.Errorf("%s: expected a result and an optional error, got %v", name, typ)
+		}
+		for i := 0; i < typ.NumIn(); i++ {
+			switch typ.In(i).Kind() {
+			case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+				t.Errorf("%s: testcases can't pass argument %d of type %v", name, i, typ.In(i))
+			}
+		}
+	}
+}
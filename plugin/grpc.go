@@ -8,144 +8,318 @@ import (
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/grpcreflect"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"io"
 	"net/http"
 	"strings"
-	"sync"
-	"time"
-)
-
-var (
-	resourceMap     = make(map[string]grpcurl.DescriptorSource)
-	ccMap           = make(map[string]*grpc.ClientConn)
-	ctxMap          = make(map[string]context.Context)
-	refClientMap    = make(map[string]*grpcreflect.Client)
-	resourceRWMutex sync.RWMutex
 )
 
 type Grpc struct {
-	Host     string
+	Host string
+	// Method is the fully-qualified name of the method to invoke, e.g.
+	// user.User.Login. The slash form user.User/Login, a unique short name
+	// such as User.Login or Login, and names differing only in case are
+	// resolved as well.
 	Method   string
 	Metadata []RpcMetadata
 	Timeout  float32
 	Body     io.Reader
+	// TLS secures the connection to Host, nil means plaintext.
+	TLS *TLSConfig
+	// Dial tunes how the connection to Host is established.
+	Dial *DialConfig
+	// Messages supplies the requests of a client-streaming or bidi method
+	// instead of Body.
+	Messages MessageSource
+	// Conversation scripts the requests of a bidi method from the responses,
+	// instead of Body.
+	Conversation Conversation
+	// SendInterval is the pause in seconds between the requests of a
+	// client-streaming or bidi method.
+	SendInterval float32
+	// ProtoFiles, compiled with ImportPaths as import paths, and Protosets,
+	// files holding encoded FileDescriptorSets, describe the services of
+	// Host. They resolve the symbols that server reflection doesn't know,
+	// and replace reflection if the server doesn't support it or
+	// DisableReflection is set. Files uploaded for Host with SaveProtoFile
+	// are used the same way.
+	ImportPaths       []string
+	ProtoFiles        []string
+	Protosets         []string
+	DisableReflection bool
+	// ReflectMetadata is sent with the server reflection requests only, e.g.
+	// the auth token of a gateway guarding reflection. Metadata is sent with
+	// the RPC only.
+	ReflectMetadata []RpcMetadata
+	// ReflectMetadataProvider is called whenever a reflection stream is
+	// opened, and the metadata it returns is sent along with
+	// ReflectMetadata, so that expiring tokens are refreshed.
+	ReflectMetadataProvider MetadataProvider
+	// ReflectMetadataKey identifies the credentials ReflectMetadataProvider
+	// returns. Requests with a provider only share a connection when they
+	// have the same key, or without one when they use the same Grpc.
+	ReflectMetadataKey string
+	// Validation selects how request bodies are checked against the request
+	// type before they are sent, ValidationStrict by default.
+	Validation ValidationMode
+}
+
+// Request holds the values of a single call. Unlike the same fields of
+// Grpc, a Request is not shared between calls, so concurrent calls made with
+// InvokeGrpc.Call each get their own.
+type Request struct {
+	Method       string
+	Metadata     []RpcMetadata
+	Timeout      float32
+	Body         io.Reader
+	Messages     MessageSource
+	Conversation Conversation
+	SendInterval float32
+	Validation   ValidationMode
+}
+
+func (g *Grpc) request() Request {
+	return Request{
+		Method:       g.Method,
+		Metadata:     g.Metadata,
+		Timeout:      g.Timeout,
+		Body:         g.Body,
+		Messages:     g.Messages,
+		Conversation: g.Conversation,
+		SendInterval: g.SendInterval,
+		Validation:   g.Validation,
+	}
 }
 
+// InvokeGrpc calls the methods of G.Host. It is safe for concurrent use as
+// long as G is not modified, but calls made with InvokeFunction all read
+// G.Body; concurrent calls should use Call with a Request each.
 type InvokeGrpc struct {
-	G          *Grpc
-	descSource grpcurl.DescriptorSource
-	cc         *grpc.ClientConn
-	ctx        context.Context
-	refClient  *grpcreflect.Client
+	G        *Grpc
+	registry *Registry
 }
 
 func NewInvokeGrpc(g *Grpc) *InvokeGrpc {
-	return &InvokeGrpc{G: g}
+	return NewInvokeGrpcWithRegistry(g, DefaultRegistry)
 }
 
-func (i *InvokeGrpc) GetResource() (err error) {
-	resourceRWMutex.RLock()
-	res := resourceMap[i.G.Host]
-	resourceRWMutex.RUnlock()
-	if res == nil {
-		resourceRWMutex.Lock()
-		defer resourceRWMutex.Unlock()
-		res = resourceMap[i.G.Host]
-		if res == nil {
-			err = i.getClient()
-			if err != nil {
-				return
-			}
-			resourceMap[i.G.Host] = i.descSource
-			ccMap[i.G.Host] = i.cc
-			ctxMap[i.G.Host] = i.ctx
-			refClientMap[i.G.Host] = i.refClient
-		}
+// NewInvokeGrpcWithRegistry creates an InvokeGrpc whose connections are owned
+// by r instead of DefaultRegistry.
+func NewInvokeGrpcWithRegistry(g *Grpc, r *Registry) *InvokeGrpc {
+	return &InvokeGrpc{G: g, registry: r}
+}
+
+func (i *InvokeGrpc) getRegistry() *Registry {
+	if i.registry == nil {
+		return DefaultRegistry
 	}
-	i.descSource = resourceMap[i.G.Host]
-	i.cc = ccMap[i.G.Host]
-	i.ctx = ctxMap[i.G.Host]
-	i.refClient = refClientMap[i.G.Host]
+	return i.registry
+}
+
+func (i *InvokeGrpc) GetResource() (err error) {
+	return i.GetResourceContext(context.Background())
+}
+
+// GetResourceContext is like GetResource, but gives up waiting for the
+// connection when ctx is done.
+func (i *InvokeGrpc) GetResourceContext(ctx context.Context) (err error) {
+	_, err = i.getClient(ctx)
 	return
 }
 
-func (i *InvokeGrpc) getClient() (err error) {
-	dialTime := 10 * time.Second
-	keepaliveTime := 0.0
-	maxMsgSz := 1024 * 1024 * 256
+// getClient returns the registry's client for G. Every call asks the
+// registry, so clients it evicted or reset are not used any longer.
+func (i *InvokeGrpc) getClient(ctx context.Context) (*Client, error) {
+	c, release, err := i.acquireClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	release()
+	return c, nil
+}
+
+// acquireClient is like getClient, but the client is kept open until
+// released, see Registry.acquire.
+func (i *InvokeGrpc) acquireClient(ctx context.Context) (*Client, func(), error) {
+	return i.getRegistry().acquire(ctx, i.G)
+}
+
+func newClient(g *Grpc) (c *Client, err error) {
+	fileSource, err := g.fileSource()
+	if err != nil {
+		return nil, &Error{Kind: ErrReflection, Subject: g.Host, Err: err}
+	}
+	if g.DisableReflection && fileSource == nil {
+		return nil, &Error{Kind: ErrReflection, Subject: g.Host, Err: errors.New("reflection is disabled, but no proto files or protosets are given")}
+	}
+
 	ctx := context.Background()
-	dialCtx, cancel := context.WithTimeout(ctx, dialTime)
+	dialCtx, cancel := context.WithTimeout(ctx, g.Dial.timeout())
 	defer cancel()
-	var opts []grpc.DialOption
-
-	if keepaliveTime > 0 {
-		timeout := time.Duration(keepaliveTime * float64(time.Second))
-		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:    timeout,
-			Timeout: timeout,
-		}))
+	opts := g.Dial.dialOptions()
+	if g.ReflectMetadataProvider != nil {
+		opts = append(opts, grpc.WithChainStreamInterceptor(reflectionInterceptor(g.ReflectMetadataProvider)))
 	}
-	opts = append(opts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMsgSz)))
 
 	network := "tcp"
-	var creds credentials.TransportCredentials
-	i.cc, err = dial(dialCtx, network, i.G.Host, creds, true, opts...)
+	creds, err := g.TLS.credentials()
 	if err != nil {
-		return
+		return nil, &Error{Kind: ErrDial, Subject: g.Host, Err: err}
+	}
+	cc, err := dial(dialCtx, network, g.Host, creds, g.Dial.failFast(), opts...)
+	if err != nil {
+		return nil, &Error{Kind: ErrDial, Subject: g.Host, Err: err}
 	}
 
-	var refClient *grpcreflect.Client
+	c = &Client{
+		key:  g.connKey(),
+		host: g.Host,
+		cc:   cc,
+		ctx:  ctx,
+	}
+	if g.DisableReflection {
+		c.descSource = fileSource
+		return
+	}
 
 	addlHeaders := []string{}
-	reflHeaders := []string{}
+	reflHeaders := metadataHeaders(g.ReflectMetadata)
 	md := grpcurl.MetadataFromHeaders(append(addlHeaders, reflHeaders...))
-	refCtx := metadata.NewOutgoingContext(ctx, md)
-	refClient = grpcreflect.NewClient(refCtx, reflectpb.NewServerReflectionClient(i.cc))
-	reflSource := grpcurl.DescriptorSourceFromServer(ctx, refClient)
-	i.refClient = refClient
-	i.descSource = compositeSource{reflection: reflSource}
-	i.ctx = ctx
+	c.newSource = func(ctx context.Context) (*grpcreflect.Client, grpcurl.DescriptorSource) {
+		// speaks grpc.reflection.v1, or v1alpha if the server doesn't support it
+		refClient := grpcreflect.NewClientAuto(metadata.NewOutgoingContext(ctx, md), cc)
+		reflSource := reflectionSource{grpcurl.DescriptorSourceFromServer(ctx, refClient), g.Host}
+		return refClient, compositeSource{reflection: reflSource, file: fileSource}
+	}
+	c.refClient, c.descSource = c.newSource(ctx)
 	return
 }
 
 func (i *InvokeGrpc) InvokeFunction() (results *RpcResult, err error) {
-	err = i.GetResource()
-	if err != nil {
-		return nil, err
-	}
+	return i.InvokeFunctionContext(context.Background())
+}
+
+// InvokeFunctionContext is like InvokeFunction, but the RPC and the
+// reflection lookups it needs are bound to ctx, so the caller can cancel
+// them or give them a deadline. Grpc.Timeout still applies on top of any
+// deadline of ctx.
+func (i *InvokeGrpc) InvokeFunctionContext(ctx context.Context) (results *RpcResult, err error) {
+	return i.invoke(ctx, i.G.request(), invokeHooks{})
+}
+
+// Call is like InvokeFunctionContext, but takes the method and request from
+// req instead of G.
+func (i *InvokeGrpc) Call(ctx context.Context, req Request) (results *RpcResult, err error) {
+	return i.invoke(ctx, req, invokeHooks{})
+}
+
+func (i *InvokeGrpc) invoke(ctx context.Context, req Request, hooks invokeHooks) (results *RpcResult, err error) {
 	header := http.Header{}
-	configs, err := ComputeSvcConfigs([]string{i.G.Host}, []string{i.G.Method})
-	if err != nil {
-		return
-	}
-	descs, err := getMethods(i.descSource, configs)
-	if err != nil {
-		return
-	}
-	for _, md := range descs {
-		if md.GetFullyQualifiedName() == i.G.Method {
-			i.descSource, err = grpcurl.DescriptorSourceFromFileDescriptors(md.GetFile())
+	var input rpcInput
+	var validator *requestValidator
+	prepared := false
+	err = i.withReconnect(ctx, func(c *Client) (lost bool, err error) {
+		results = nil
+		md, idx, lost, err := c.resolveMethod(ctx, req.Method)
+		if err != nil {
+			return lost, err
+		}
 
-			var input rpcInput
-			var js []byte
-			js, err = io.ReadAll(i.G.Body)
+		if !prepared {
+			err = req.requestInput(ctx, md, &input, &hooks)
 			if err != nil {
-				return
+				return false, &Error{Kind: ErrBadInput, Subject: req.Method, Err: err}
 			}
-			input.Data = append(input.Data, js)
-			input.Metadata = i.G.Metadata
-			input.TimeoutSeconds = i.G.Timeout
-
-			results, err = invokeRPC(i.ctx, i.G.Method, i.cc, i.descSource, header, input, &InvokeOptions{})
+			validator = &requestValidator{md: md.GetInputType(), mode: req.Validation}
+			if err = validator.prepare(&input, &hooks); err != nil {
+				return false, &Error{Kind: ErrBadInput, Subject: req.Method, Err: err}
+			}
+			input.Metadata = req.Metadata
+			input.TimeoutSeconds = req.Timeout
+			prepared = true
+		}
 
-			return
+		// the call is only repeated while nothing was consumed from a
+		// request stream and no response was delivered
+		delivered := false
+		attemptHooks := hooks
+		attemptHooks.onResponse = func(resp RpcResponseElement) bool {
+			delivered = true
+			if hooks.onResponse != nil {
+				return hooks.onResponse(resp)
+			}
+			return true
+		}
+		ch := &streamTracker{cc: c.cc}
+		results, err = invokeRPCWithHooks(ctx, md.GetFullyQualifiedName(), ch, idx.source, header, input, &InvokeOptions{}, attemptHooks)
+		if results != nil {
+			results.Warnings = validator.result()
 		}
+		if hooks.next != nil || delivered {
+			return false, err
+		}
+		if err != nil {
+			lost = errConnLost(err)
+		} else {
+			lost = results.Error != nil && connLost(codes.Code(results.Error.Code))
+		}
+		return lost && resend(md, ch.sent(), i.G.Dial.retryIdempotent()), err
+	})
+	return
+}
+
+// withContext runs fn and returns its error, or ctx.Err() if ctx is done
+// first. Reflection clients are bound to the context they were created with:
+// lookups through Client.lookupSource end with ctx, while the ones through
+// the shared source of a client, such as building its method index, keep
+// running in the background and only their result is discarded.
+func withContext(ctx context.Context, fn func() error) error {
+	if ctx.Done() == nil {
+		return fn()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	return nil, errors.New("未找到对应的请求方式")
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func findSymbol(ctx context.Context, source grpcurl.DescriptorSource, name string) (desc.Descriptor, error) {
+	var d desc.Descriptor
+	err := withContext(ctx, func() (err error) {
+		d, err = source.FindSymbol(name)
+		return
+	})
+	if err != nil {
+		// an abandoned lookup may still set d
+		return nil, err
+	}
+	return d, nil
+}
+
+// findSymbol looks name up on the host of G, reconnecting if needed. It
+// also returns the source name was found in.
+func (i *InvokeGrpc) findSymbol(ctx context.Context, name string) (d desc.Descriptor, source grpcurl.DescriptorSource, err error) {
+	err = i.withReconnect(ctx, func(c *Client) (bool, error) {
+		lookup, done := c.lookupSource(ctx)
+		defer done()
+		// the descriptors found outlive the lookup, so does the source
+		// returned along with them
+		source = c.DescriptorSource()
+		var err error
+		d, err = findSymbol(ctx, lookup, name)
+		return errConnLost(err), lookupError(ctx, name, err)
+	})
+	return
 }
 
 func ComputeSvcConfigs(services, methods []string) (map[string]*svcConfig, error) {
@@ -173,12 +347,26 @@ func ComputeSvcConfigs(services, methods []string) (map[string]*svcConfig, error
 }
 
 func (i *InvokeGrpc) GetSvs() (svc []string, err error) {
-	allServices, err := i.descSource.ListServices()
+	return i.GetSvsContext(context.Background())
+}
+
+// GetSvsContext is like GetSvs, but the reflection lookup is bound to ctx.
+func (i *InvokeGrpc) GetSvsContext(ctx context.Context) (svc []string, err error) {
+	var allServices []string
+	err = i.withReconnect(ctx, func(c *Client) (bool, error) {
+		source, done := c.lookupSource(ctx)
+		defer done()
+		err := withContext(ctx, func() (err error) {
+			allServices, err = source.ListServices()
+			return
+		})
+		return errConnLost(err), lookupError(ctx, i.G.Host, err)
+	})
 	if err != nil {
 		return
 	}
 	for _, v := range allServices {
-		if v == "grpc.reflection.v1alpha.ServerReflection" {
+		if hiddenServices[v] {
 			continue
 		}
 		svc = append(svc, v)
@@ -187,14 +375,19 @@ func (i *InvokeGrpc) GetSvs() (svc []string, err error) {
 }
 
 func (i *InvokeGrpc) GetMethod(serverName string) (method []string, err error) {
+	return i.GetMethodContext(context.Background(), serverName)
+}
 
-	d, err := i.descSource.FindSymbol(serverName)
+// GetMethodContext is like GetMethod, but the reflection lookup is bound to
+// ctx.
+func (i *InvokeGrpc) GetMethodContext(ctx context.Context, serverName string) (method []string, err error) {
+	d, _, err := i.findSymbol(ctx, serverName)
 	if err != nil {
 		return nil, err
 	}
 	sd, ok := d.(*desc.ServiceDescriptor)
 	if !ok {
-		return nil, fmt.Errorf("%s should be a service descriptor but instead is a %T", d.GetFullyQualifiedName(), d)
+		return nil, notService(serverName, d)
 	}
 
 	for _, md := range sd.GetMethods() {
@@ -205,54 +398,66 @@ func (i *InvokeGrpc) GetMethod(serverName string) (method []string, err error) {
 }
 
 func (i *InvokeGrpc) GetReq(svc, method string) (results *schema, err error) {
-	d, err := i.descSource.FindSymbol(svc)
+	return i.GetReqContext(context.Background(), svc, method)
+}
+
+// GetReqContext is like GetReq, but the reflection lookup is bound to ctx.
+func (i *InvokeGrpc) GetReqContext(ctx context.Context, svc, method string) (results *schema, err error) {
+	d, source, err := i.findSymbol(ctx, svc)
 	if err != nil {
 		return nil, err
 	}
 	sd, ok := d.(*desc.ServiceDescriptor)
 	if !ok {
-		return nil, fmt.Errorf("%s should be a service descriptor but instead is a %T", d.GetFullyQualifiedName(), d)
+		return nil, notService(svc, d)
 	}
 	for _, md := range sd.GetMethods() {
 
 		if md.GetName() == method {
-			r, err := gatherMetadataForMethod(md)
-			if err != nil {
-				return nil, err
-			}
+			return methodSchema(md, source)
+		}
+	}
+	return nil, &Error{Kind: ErrMethodNotFound, Subject: svc + "." + method}
+}
 
-			results = r
+// GetMethodInfo describes method, by any name Grpc.Method accepts: its
+// request and response types with their fields, its streaming flags,
+// comments and options, and a request body template.
+func (i *InvokeGrpc) GetMethodInfo(method string) (results *schema, err error) {
+	return i.GetMethodInfoContext(context.Background(), method)
+}
 
-			tmpl := grpcurl.MakeTemplate(md.GetInputType())
-			_, formatter, err := grpcurl.RequestParserAndFormatterFor(grpcurl.Format("json"), i.descSource, true, false, nil)
-			if err != nil {
-				return results, err
-			}
+// GetMethodInfoContext is like GetMethodInfo, but the reflection lookup is
+// bound to ctx.
+func (i *InvokeGrpc) GetMethodInfoContext(ctx context.Context, method string) (results *schema, err error) {
+	md, idx, err := i.resolve(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	return methodSchema(md, idx.source)
+}
 
-			str, _ := formatter(tmpl)
-			str = strings.Replace(str, " ", "", -1)
-			str = strings.Replace(str, "\n", "", -1)
-			results.Body = str
-			break
-		}
+// methodSchema describes md along with a request body template.
+func methodSchema(md *desc.MethodDescriptor, source grpcurl.DescriptorSource) (*schema, error) {
+	results, err := gatherMetadataForMethod(md)
+	if err != nil {
+		return nil, err
 	}
-	if results == nil {
-		return nil, errors.New("results 为空")
+
+	tmpl := grpcurl.MakeTemplate(md.GetInputType())
+	_, formatter, err := grpcurl.RequestParserAndFormatterFor(grpcurl.Format("json"), source, true, false, strings.NewReader(""))
+	if err != nil {
+		return results, err
 	}
 
-	return
+	str, _ := formatter(tmpl)
+	str = strings.Replace(str, " ", "", -1)
+	str = strings.Replace(str, "\n", "", -1)
+	results.Body = str
+	return results, nil
 }
 
 func (i *InvokeGrpc) Reset() (err error) {
-	resourceRWMutex.Lock()
-	defer resourceRWMutex.Unlock()
-	err = i.getClient()
-	if err != nil {
-		return
-	}
-	resourceMap[i.G.Host] = i.descSource
-	ccMap[i.G.Host] = i.cc
-	ctxMap[i.G.Host] = i.ctx
-	refClientMap[i.G.Host] = i.refClient
-	return err
+	_, err = i.getRegistry().Reset(i.G)
+	return
 }
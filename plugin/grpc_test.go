@@ -1,16 +1,81 @@
 package plugin
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/test-instructor/grpc-plugin/demo"
+	"google.golang.org/grpc"
 	"math/rand"
+	"net"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
+// startTestSvc serves the demo User service on a random local port and
+// returns its address. The server is stopped when the test finishes.
+func startTestSvc(t testing.TB, opt ...grpc.ServerOption) string {
+	t.Helper()
+	return serveTestSvc(t, demo.NewSvc(opt...))
+}
+
+// serveTestSvc serves s on a random local port until the test ends.
+func serveTestSvc(t testing.TB, s *grpc.Server) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// parseTestProto parses the proto file name from files, which holds the
+// sources of it and of its imports by file name.
+func parseTestProto(t testing.TB, name string, files map[string]string) *desc.FileDescriptor {
+	t.Helper()
+	p := protoparse.Parser{Accessor: protoparse.FileContentsFromMap(files), IncludeSourceCodeInfo: true}
+	fds, err := p.ParseFiles(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fds[0]
+}
+
+// registryClient returns the client r holds for the connection key of g, or
+// nil if it holds none yet.
+func registryClient(r *Registry, g *Grpc) *Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.clients[g.connKey()]
+	if !ok {
+		return nil
+	}
+	f := e.Value.(*clientFuture)
+	select {
+	case <-f.ready:
+		return f.client
+	default:
+		return nil
+	}
+}
+
+var nameSeq int64
+
+// uniqueName returns prefix with a suffix no other call of the process gets,
+// for users registered in the process-wide user DB of the demo, so that
+// tests pass when run more than once.
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, atomic.AddInt64(&nameSeq, 1))
+}
+
 func TestGrpcConnect(t *testing.T) {
 	go demo.StartSvc()
 	defer demo.StopSvc()
@@ -98,8 +163,8 @@ func TestServer(t *testing.T) {
 }
 
 func TestServerReset(t *testing.T) {
-	//go demo.StartSvc()
-	//defer demo.StopSvc()
+	go demo.StartSvc()
+	defer demo.StopSvc()
 	var g = &Grpc{}
 	g.Host = "127.0.0.1:40061"
 	g.Timeout = 1.0
@@ -139,3 +204,126 @@ func TestServerReset(t *testing.T) {
 	fmt.Println(string(resultsJson2))
 
 }
+
+func TestDialConfig(t *testing.T) {
+	addr := startTestSvc(t)
+
+	g := &Grpc{Host: addr, Dial: &DialConfig{
+		Timeout:         2,
+		KeepaliveTime:   30,
+		UserAgent:       "grpc-plugin-test",
+		WaitForReady:    true,
+		DisableFailFast: true,
+	}}
+	svc, err := listServices(t, g)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(svc) != 1 || svc[0] != "user.User" {
+		t.Fatalf("unexpected services: %v", svc)
+	}
+
+	// reflection responses don't fit in 10 bytes, so this must not reuse
+	// the connection above
+	small := &Grpc{Host: addr, Dial: &DialConfig{MaxRecvMsgSize: 10}}
+	if small.connKey() == g.connKey() {
+		t.Fatal("connections with different dial configs share a cache key")
+	}
+	if _, err := listServices(t, small); err == nil {
+		t.Fatal("expected error for responses larger than MaxRecvMsgSize")
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	// a listener that never speaks HTTP/2 makes the dial block until timeout
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	start := time.Now()
+	ig := NewInvokeGrpc(&Grpc{Host: lis.Addr().String(), Dial: &DialConfig{Timeout: 0.2}})
+	if err := ig.GetResource(); err == nil {
+		t.Fatal("expected dial to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("dial took %v, expected it to honour the 200ms timeout", elapsed)
+	}
+}
+
+func TestInvokeFunctionContext(t *testing.T) {
+	addr := startTestSvc(t)
+	g := &Grpc{
+		Host:   addr,
+		Method: "user.User.RegisterUser",
+		Body:   strings.NewReader(`{"UserName": "ctx-` + strconv.Itoa(rand.Intn(1000000)) + `"}`),
+	}
+	ig := NewInvokeGrpc(g)
+	res, err := ig.InvokeFunctionContext(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Error != nil || len(res.Responses) != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g.Body = strings.NewReader(`{}`)
+	if _, err := ig.InvokeFunctionContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := ig.GetSvsContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestLookupContext(t *testing.T) {
+	var block int32
+	cancelled := make(chan struct{}, 1)
+	addr := startTestSvc(t, grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if atomic.LoadInt32(&block) == 0 || !strings.Contains(info.FullMethod, "ServerReflection") {
+			return handler(srv, ss)
+		}
+		if _, ok := ss.Context().Deadline(); !ok {
+			t.Error("expected the deadline of the lookup to reach the reflection stream")
+		}
+		<-ss.Context().Done()
+		cancelled <- struct{}{}
+		return ss.Context().Err()
+	}))
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r)
+	if _, err := ig.GetSvs(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	atomic.StoreInt32(&block, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := ig.GetMethodContext(ctx, "user.User"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the reflection stream to be cancelled with the lookup")
+	}
+}
+
+func TestGetResourceContextDeadline(t *testing.T) {
+	dead, _ := blackholeListener(t)
+	ig := NewInvokeGrpc(&Grpc{Host: dead})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := ig.GetResourceContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("waited %v despite the 100ms deadline", elapsed)
+	}
+}
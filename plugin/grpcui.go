@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/fullstorydev/grpcurl"
 	"github.com/jhump/protoreflect/desc"
@@ -77,19 +78,33 @@ type compositeSource struct {
 }
 
 func (cs compositeSource) ListServices() ([]string, error) {
-	return cs.reflection.ListServices()
+	svcs, err := cs.reflection.ListServices()
+	if err != nil && cs.file != nil {
+		// the server doesn't support reflection, use the files alone
+		return cs.file.ListServices()
+	}
+	return svcs, err
 }
 
 func (cs compositeSource) FindSymbol(fullyQualifiedName string) (desc.Descriptor, error) {
 	d, err := cs.reflection.FindSymbol(fullyQualifiedName)
-	if err == nil {
-		return d, nil
+	if err == nil || cs.file == nil {
+		return d, err
+	}
+	d, fileErr := cs.file.FindSymbol(fullyQualifiedName)
+	var unauthorized *ReflectionUnauthorizedError
+	if fileErr != nil && errors.As(err, &unauthorized) {
+		// the symbol may well exist, reflection just wasn't allowed to say
+		return nil, err
 	}
-	return cs.file.FindSymbol(fullyQualifiedName)
+	return d, fileErr
 }
 
 func (cs compositeSource) AllExtensionsForType(typeName string) ([]*desc.FieldDescriptor, error) {
 	exts, err := cs.reflection.AllExtensionsForType(typeName)
+	if cs.file == nil {
+		return exts, err
+	}
 	if err != nil {
 		// On error fall back to file source
 		return cs.file.AllExtensionsForType(typeName)
@@ -151,26 +166,26 @@ func fail(err error, msg string, args ...interface{}) {
 	}
 }
 
-func checkAssetNames(soFar map[string]string, names []string, requireFile bool) {
+func checkAssetNames(soFar map[string]string, names []string, requireFile bool) error {
 	for _, n := range names {
 		st, err := os.Stat(n)
 		if err != nil {
 			if os.IsNotExist(err) {
-				fail(nil, "File %q does not exist", n)
-			} else {
-				fail(err, "Failed to check existence of file %q", n)
+				return fmt.Errorf("file %q does not exist", n)
 			}
+			return fmt.Errorf("failed to check existence of file %q: %v", n, err)
 		}
 		if requireFile && st.IsDir() {
-			fail(nil, "Path %q is a folder, not a file", n)
+			return fmt.Errorf("path %q is a folder, not a file", n)
 		}
 
 		base := filepath.Base(n)
 		if existing, ok := soFar[base]; ok {
-			fail(nil, "Multiple assets with the same base name specified: %s and %s", existing, n)
+			return fmt.Errorf("multiple assets with the same base name specified: %s and %s", existing, n)
 		}
 		soFar[base] = n
 	}
+	return nil
 }
 
 func configureJSandCSS(names []string, fn func(string, func() (io.ReadCloser, error)) standalone.HandlerOption) []standalone.HandlerOption {
@@ -185,13 +200,13 @@ func configureJSandCSS(names []string, fn func(string, func() (io.ReadCloser, er
 	return opts
 }
 
-func configureAssets(names []string) []standalone.HandlerOption {
+func configureAssets(names []string) ([]standalone.HandlerOption, error) {
 	opts := make([]standalone.HandlerOption, len(names))
 	for i := range names {
 		name := names[i] // no loop variable so that we don't close over loop var in lambdas below
 		st, err := os.Stat(name)
 		if err != nil {
-			fail(err, "failed to inspect file %q", name)
+			return nil, fmt.Errorf("failed to inspect file %q: %v", name, err)
 		}
 		if st.IsDir() {
 			open := func(p string) (io.ReadCloser, error) {
@@ -213,7 +228,7 @@ func configureAssets(names []string) []standalone.HandlerOption {
 			opts[i] = standalone.ServeAssetFile(filepath.Base(name), open)
 		}
 	}
-	return opts
+	return opts, nil
 }
 
 type svcConfig struct {
@@ -221,15 +236,26 @@ type svcConfig struct {
 	includeMethods map[string]struct{}
 }
 
+// hiddenServices are the infrastructure services a server may expose next to
+// its own, they are left out of service listings.
+var hiddenServices = map[string]bool{
+	"grpc.reflection.v1.ServerReflection":      true,
+	"grpc.reflection.v1alpha.ServerReflection": true,
+	"grpc.health.v1.Health":                    true,
+	"grpc.channelz.v1.Channelz":                true,
+}
+
 func getMethods(source grpcurl.DescriptorSource, configs map[string]*svcConfig) ([]*desc.MethodDescriptor, error) {
 	allServices, err := source.ListServices()
 	if err != nil {
 		return nil, err
 	}
 
+	// configs shrinks as services are found, remember whether it filters
+	filtered := len(configs) != 0
 	var descs []*desc.MethodDescriptor
 	for _, svc := range allServices {
-		if svc == "grpc.reflection.v1alpha.ServerReflection" {
+		if hiddenServices[svc] && configs[svc] == nil {
 			continue
 		}
 		d, err := source.FindSymbol(svc)
@@ -238,10 +264,10 @@ func getMethods(source grpcurl.DescriptorSource, configs map[string]*svcConfig)
 		}
 		sd, ok := d.(*desc.ServiceDescriptor)
 		if !ok {
-			return nil, fmt.Errorf("%s should be a service descriptor but instead is a %T", d.GetFullyQualifiedName(), d)
+			return nil, notService(svc, d)
 		}
 		cfg := configs[svc]
-		if cfg == nil && len(configs) != 0 {
+		if cfg == nil && filtered {
 			// not configured to expose this service
 			continue
 		}
@@ -352,8 +378,25 @@ func (cs *codeSniffer) WriteHeader(statusCode int) {
 }
 
 func dial(ctx context.Context, network, addr string, creds credentials.TransportCredentials, failFast bool, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	// track handshake errors on both paths, so that a failed TLS handshake is
+	// reported as such rather than as a generic connection failure
+	var errCreds *errTrackingCreds
+	if creds != nil {
+		errCreds = &errTrackingCreds{
+			TransportCredentials: creds,
+		}
+		creds = errCreds
+	}
+
 	if failFast {
-		return grpcurl.BlockingDial(ctx, network, addr, creds, opts...)
+		cc, err := grpcurl.BlockingDial(ctx, network, addr, creds, opts...)
+		if err != nil {
+			if herr := errCreds.err(); herr != nil {
+				return nil, tlsHandshakeError{addr: addr, err: herr}
+			}
+			return nil, err
+		}
+		return cc, nil
 	}
 	// BlockingDial will return the first error returned. It is meant for interactive use.
 	// If we don't want to fail fast, then we need to do a more customized dial.
@@ -365,14 +408,10 @@ func dial(ctx context.Context, network, addr string, creds credentials.Transport
 		dialer:  &net.Dialer{},
 		network: network,
 	}
-	var errCreds errTrackingCreds
 	if creds == nil {
 		opts = append(opts, grpc.WithTransportCredentials(insecurecreds.NewCredentials()))
 	} else {
-		errCreds = errTrackingCreds{
-			TransportCredentials: creds,
-		}
-		opts = append(opts, grpc.WithTransportCredentials(&errCreds))
+		opts = append(opts, grpc.WithTransportCredentials(creds))
 	}
 
 	cc, err := grpc.DialContext(ctx, addr, append(opts, grpc.WithBlock(), grpc.WithContextDialer(dialer.dial))...)
@@ -382,7 +421,7 @@ func dial(ctx context.Context, network, addr string, creds credentials.Transport
 
 	// prefer last observed TLS handshake error if there is one
 	if err := errCreds.err(); err != nil {
-		return nil, err
+		return nil, tlsHandshakeError{addr: addr, err: err}
 	}
 	// otherwise, use the error the dialer last observed
 	if err := dialer.err(); err != nil {
@@ -410,6 +449,9 @@ func (c *errTrackingCreds) ClientHandshake(ctx context.Context, addr string, raw
 }
 
 func (c *errTrackingCreds) err() error {
+	if c == nil {
+		return nil
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	return c.lastErr
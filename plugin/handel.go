@@ -5,10 +5,12 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/jhump/protoreflect/desc/protoprint"
 	"github.com/test-instructor/grpc-plugin/plugin/internal"
 	"io"
+	"log"
 	"math"
 	"net/http"
 	"sort"
@@ -60,10 +62,16 @@ type InvokeOptions struct {
 // for a specified method.
 //
 // The handler accepts GET requests, using a query parameter to indicate the
-// method whose schema metadata should be fetched. The response payload will be
-// JSON. The format of the response body matches the format expected by the
-// JavaScript client code embedded in WebFormContents.
+// method whose schema metadata should be fetched, by any name Grpc.Method
+// accepts. The response payload will be JSON, describing the request and
+// response types and the method itself. The format of the response body
+// matches the format expected by the JavaScript client code embedded in
+// WebFormContents.
 func RPCMetadataHandler(methods []*desc.MethodDescriptor, files []*desc.FileDescriptor) http.Handler {
+	idx := &methodIndex{methods: map[string]*desc.MethodDescriptor{}}
+	for _, md := range methods {
+		idx.methods[md.GetFullyQualifiedName()] = md
+	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" {
 			w.Header().Set("Allow", "GET")
@@ -77,19 +85,14 @@ func RPCMetadataHandler(methods []*desc.MethodDescriptor, files []*desc.FileDesc
 			// This means gather *all* message types. This is used to
 			// provide a drop-down for Any messages.
 			results = gatherAllMessageMetadata(files)
-		} else {
-			for _, md := range methods {
-				if md.GetFullyQualifiedName() == method {
-					r, err := gatherMetadataForMethod(md)
-					if err != nil {
-						http.Error(w, "Failed to gather metadata for RPC Method", http.StatusUnprocessableEntity)
-						return
-					}
-
-					results = r
-					break
-				}
+		} else if md, err := idx.resolve(method); err == nil {
+			r, err := gatherMetadataForMethod(md)
+			if err != nil {
+				http.Error(w, "Failed to gather metadata for RPC Method", http.StatusUnprocessableEntity)
+				return
 			}
+
+			results = r
 		}
 
 		if results == nil {
@@ -97,25 +100,163 @@ func RPCMetadataHandler(methods []*desc.MethodDescriptor, files []*desc.FileDesc
 			return
 		}
 
+		writeJSON(w, results)
+	})
+}
+
+// RPCOpenAPIHandler returns an HTTP handler that serves the OpenAPI
+// document of methods, see NewOpenAPIDocument.
+//
+// The handler accepts GET requests. The document is generated once and
+// served as JSON.
+func RPCOpenAPIHandler(methods []*desc.MethodDescriptor, opts OpenAPIOptions) http.Handler {
+	doc, err := json.MarshalIndent(NewOpenAPIDocument(methods, opts), "", "  ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to generate OpenAPI document", http.StatusInternalServerError)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		enc := json.NewEncoder(w)
+		w.Write(doc)
+	})
+}
+
+// RPCInvokeHandler returns an HTTP handler that can be used to invoke RPCs
+// of methods over ch.
+//
+// The handler accepts POST requests to /invoke/{method}, or /{method} when
+// mounted with http.StripPrefix, naming the method by any name Grpc.Method
+// accepts. The request payload is the JSON sent by the JavaScript client
+// code embedded in WebFormContents: the request messages along with
+// metadata and a timeout. Metadata configured in options overrides the
+// metadata of the payload. The response payload will be JSON, describing
+// the response headers, messages, trailers and status of the RPC.
+func RPCInvokeHandler(ch grpc.ClientConnInterface, methods []*desc.MethodDescriptor, options InvokeOptions) http.Handler {
+	idx := &methodIndex{methods: map[string]*desc.MethodDescriptor{}}
+	var files []*desc.FileDescriptor
+	seen := map[string]bool{}
+	for _, md := range methods {
+		idx.methods[md.GetFullyQualifiedName()] = md
+		if fd := md.GetFile(); !seen[fd.GetName()] {
+			seen[fd.GetName()] = true
+			files = append(files, fd)
+		}
+	}
+	var srcErr error
+	idx.source, srcErr = grpcurl.DescriptorSourceFromFileDescriptors(files...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if srcErr != nil {
+			http.Error(w, "Failed to create descriptor source", http.StatusInternalServerError)
+			return
+		}
+
+		// /invoke is a whole segment, a method may start with it too
+		method := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/"), "invoke/")
+		md, err := idx.resolve(method)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		input, err := readInvokeInput(r.Body, md)
+		if err != nil {
+			if _, ok := err.(errReadFail); ok {
+				http.Error(w, "Failed to read request", 499)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		enc.SetIndent("", "  ")
-		// TODO: what if enc.Encode returns a non-I/O error?
-		enc.Encode(results)
+		results, err := invokeRPC(r.Context(), md.GetFullyQualifiedName(), ch, idx.source, r.Header, input, &options)
+		if err != nil {
+			if errors.Is(err, ErrBadInput) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		writeJSON(w, results)
 	})
 }
 
+// writeJSON answers with v as indented JSON. v is encoded before anything
+// is written, so that a value that fails to encode is answered with an
+// error rather than a truncated body. Failures to write are only logged,
+// the client is unlikely to be listening anymore.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Printf("failed to encode response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// readInvokeInput reads the payload of an invoke request and validates its
+// request messages against md.
+func readInvokeInput(body io.Reader, md *desc.MethodDescriptor) (rpcInput, error) {
+	var input rpcInput
+	js, err := io.ReadAll(body)
+	if err != nil {
+		return input, errReadFail{err: err}
+	}
+	if err := json.Unmarshal(js, &input); err != nil {
+		return input, errBadInput{err: fmt.Errorf("failed to parse JSON: %v", err)}
+	}
+	if len(input.Data) != 1 && !md.IsClientStreaming() {
+		return input, errBadInput{err: fmt.Errorf("%s takes exactly one request message, got %d", md.GetFullyQualifiedName(), len(input.Data))}
+	}
+	rv := &requestValidator{md: md.GetInputType()}
+	for n, data := range input.Data {
+		cleaned, err := rv.validate(data, n)
+		if err != nil {
+			return input, errBadInput{err: err}
+		}
+		input.Data[n] = cleaned
+	}
+	return input, nil
+}
+
 // TODO(jaime, jhump): schema is playing double duty here. It's both a vehicle for all
 // message and enum metadata. As well as RPC method scoped metadata for a single method.
 // What if we wanted to load metadata for all methods? We should consider splitting this
 // into 2 separate types for metadata to respond with accordingly.
 type schema struct {
-	RequestType   string                  `json:"requestType"`
-	RequestStream bool                    `json:"requestStream"`
-	MessageTypes  map[string][]fieldDef   `json:"messageTypes"`
-	EnumTypes     map[string][]enumValDef `json:"enumTypes"`
-	Body          string                  `json:"body"`
+	RequestType    string `json:"requestType"`
+	RequestStream  bool   `json:"requestStream"`
+	ResponseType   string `json:"responseType"`
+	ResponseStream bool   `json:"responseStream"`
+	// FullName, the comments and the options describe the method. The
+	// comments are only known if the descriptors have source info, which
+	// proto files do, but reflection usually doesn't.
+	FullName         string                  `json:"fullName"`
+	LeadingComments  string                  `json:"leadingComments"`
+	TrailingComments string                  `json:"trailingComments"`
+	Deprecated       bool                    `json:"deprecated"`
+	IdempotencyLevel string                  `json:"idempotencyLevel"`
+	MessageTypes     map[string][]fieldDef   `json:"messageTypes"`
+	EnumTypes        map[string][]enumValDef `json:"enumTypes"`
+	Body             string                  `json:"body"`
 }
 
 type fieldDef struct {
@@ -197,13 +338,21 @@ func gatherAllMessages(msgs []*desc.MessageDescriptor, result *schema) {
 func gatherMetadataForMethod(md *desc.MethodDescriptor) (*schema, error) {
 	msg := md.GetInputType()
 	result := &schema{
-		RequestType:   msg.GetFullyQualifiedName(),
-		RequestStream: md.IsClientStreaming(),
-		MessageTypes:  map[string][]fieldDef{},
-		EnumTypes:     map[string][]enumValDef{},
+		RequestType:      msg.GetFullyQualifiedName(),
+		RequestStream:    md.IsClientStreaming(),
+		ResponseType:     md.GetOutputType().GetFullyQualifiedName(),
+		ResponseStream:   md.IsServerStreaming(),
+		FullName:         md.GetFullyQualifiedName(),
+		LeadingComments:  md.GetSourceInfo().GetLeadingComments(),
+		TrailingComments: md.GetSourceInfo().GetTrailingComments(),
+		Deprecated:       md.GetMethodOptions().GetDeprecated(),
+		IdempotencyLevel: md.GetMethodOptions().GetIdempotencyLevel().String(),
+		MessageTypes:     map[string][]fieldDef{},
+		EnumTypes:        map[string][]enumValDef{},
 	}
 
 	result.visitMessage(msg)
+	result.visitMessage(md.GetOutputType())
 
 	return result, nil
 }
@@ -287,7 +436,10 @@ func (s *schema) processField(fd *desc.FieldDescriptor) fieldDef {
 		def.Type = typeMap[fd.GetType()]
 	}
 
-	desc, err := protoPrinter.PrintProtoToString(fd)
+	// the printer normalizes its settings on every use, so each call gets
+	// its own copy
+	printer := protoPrinter
+	desc, err := printer.PrintProtoToString(fd)
 	if err != nil {
 		// generate simple description with no comments or options
 		var label string
@@ -351,18 +503,59 @@ func (e errReadFail) Error() string {
 }
 
 func invokeRPC(ctx context.Context, methodName string, ch grpc.ClientConnInterface, descSource grpcurl.DescriptorSource, reqHdrs http.Header, input rpcInput, options *InvokeOptions) (*RpcResult, error) {
+	return invokeRPCWithHooks(ctx, methodName, ch, descSource, reqHdrs, input, options, invokeHooks{})
+}
+
+// invokeHooks let callers feed and observe an RPC while it runs, which is
+// what streaming RPCs need.
+type invokeHooks struct {
+	// next supplies the request messages, returning io.EOF after the last
+	// one. input.Data is used when it is nil.
+	next func() (json.RawMessage, error)
+	// onResponse is called with each response as soon as it arrives. The
+	// response is dropped if it returns false.
+	onResponse func(RpcResponseElement) bool
+	// discardResponses stops responses from being collected in
+	// RpcResult.Responses, for callers that consume them via onResponse.
+	discardResponses bool
+	// onTrailers is called once the server has ended the RPC.
+	onTrailers func()
+}
+
+func invokeRPCWithHooks(ctx context.Context, methodName string, ch grpc.ClientConnInterface, descSource grpcurl.DescriptorSource, reqHdrs http.Header, input rpcInput, options *InvokeOptions, hooks invokeHooks) (*RpcResult, error) {
 	reqStats := rpcRequestStats{
 		Total: len(input.Data),
 	}
+	next := hooks.next
+	if next == nil {
+		next = func() (json.RawMessage, error) {
+			if len(input.Data) == 0 {
+				return nil, io.EOF
+			}
+			req := input.Data[0]
+			input.Data = input.Data[1:]
+			return req, nil
+		}
+	}
+	// grpcurl doesn't wrap the errors of requestFunc, so they are kept
+	// aside to be reported as ErrBadInput
+	var inputErr error
 	requestFunc := func(m proto.Message) error {
-		if len(input.Data) == 0 {
-			return io.EOF
+		req, err := next()
+		if err != nil {
+			if err != io.EOF {
+				inputErr = err
+			}
+			return err
 		}
 		reqStats.Sent++
-		req := input.Data[0]
-		input.Data = input.Data[1:]
+		if hooks.next != nil {
+			// the number of messages isn't known up front
+			reqStats.Total = reqStats.Sent
+		}
 		if err := jsonpb.Unmarshal(bytes.NewReader(req), m); err != nil {
-			return status.Errorf(codes.InvalidArgument, err.Error())
+			inputErr = status.Errorf(codes.InvalidArgument, err.Error())
+			return inputErr
 		}
 		return nil
 	}
@@ -386,9 +579,13 @@ func invokeRPC(ctx context.Context, methodName string, ch grpc.ClientConnInterfa
 
 	result := RpcResult{
 		descSource: descSource,
+		hooks:      hooks,
 		Requests:   &reqStats,
 	}
 	if err := grpcurl.InvokeRPC(ctx, descSource, ch, methodName, invokeHdrs, &result, requestFunc); err != nil {
+		if inputErr != nil && ctx.Err() == nil {
+			return nil, &Error{Kind: ErrBadInput, Subject: methodName, Err: inputErr}
+		}
 		return nil, err
 	}
 
@@ -449,7 +646,7 @@ type rpcInput struct {
 	Data           []json.RawMessage `json:"data"`
 }
 
-type rpcResponseElement struct {
+type RpcResponseElement struct {
 	Data    json.RawMessage `json:"message"`
 	IsError bool            `json:"isError"`
 }
@@ -459,20 +656,42 @@ type rpcRequestStats struct {
 	Sent  int `json:"sent"`
 }
 
-type rpcError struct {
+// RpcError is the status of an RPC that didn't end with OK. It matches
+// ErrRPCStatus with errors.Is.
+type RpcError struct {
 	Code    uint32               `json:"code"`
 	Name    string               `json:"name"`
 	Message string               `json:"message"`
-	Details []rpcResponseElement `json:"details"`
+	Details []RpcResponseElement `json:"details"`
+}
+
+func (e *RpcError) Error() string {
+	return fmt.Sprintf("rpc error: code = %s desc = %s", e.Name, e.Message)
+}
+
+func (e *RpcError) Is(target error) bool {
+	return target == ErrRPCStatus
 }
 
 type RpcResult struct {
 	descSource grpcurl.DescriptorSource
+	hooks      invokeHooks
 	Headers    []RpcMetadata        `json:"headers"`
-	Error      *rpcError            `json:"error"`
-	Responses  []rpcResponseElement `json:"responses"`
+	Error      *RpcError            `json:"error"`
+	Responses  []RpcResponseElement `json:"responses"`
 	Requests   *rpcRequestStats     `json:"requests"`
 	Trailers   []RpcMetadata        `json:"trailers"`
+	// Warnings lists what was dropped from the requests in
+	// ValidationLenient mode.
+	Warnings []ValidationProblem `json:"warnings,omitempty"`
+}
+
+// Err returns Error as an error, or nil if the RPC succeeded.
+func (r *RpcResult) Err() error {
+	if r.Error == nil {
+		return nil
+	}
+	return r.Error
 }
 
 func (*RpcResult) OnResolveMethod(*desc.MethodDescriptor) {}
@@ -484,12 +703,21 @@ func (r *RpcResult) OnReceiveHeaders(md metadata.MD) {
 }
 
 func (r *RpcResult) OnReceiveResponse(m proto.Message) {
-	r.Responses = append(r.Responses, responseToJSON(r.descSource, m))
+	resp := responseToJSON(r.descSource, m)
+	if r.hooks.onResponse != nil && !r.hooks.onResponse(resp) {
+		return
+	}
+	if !r.hooks.discardResponses {
+		r.Responses = append(r.Responses, resp)
+	}
 }
 
 func (r *RpcResult) OnReceiveTrailers(stat *status.Status, md metadata.MD) {
 	r.Trailers = responseMetadata(md)
 	r.Error = toRpcError(r.descSource, stat)
+	if r.hooks.onTrailers != nil {
+		r.hooks.onTrailers()
+	}
 }
 
 func responseMetadata(md metadata.MD) []RpcMetadata {
@@ -511,17 +739,17 @@ func responseMetadata(md metadata.MD) []RpcMetadata {
 	return ret
 }
 
-func toRpcError(descSource grpcurl.DescriptorSource, stat *status.Status) *rpcError {
+func toRpcError(descSource grpcurl.DescriptorSource, stat *status.Status) *RpcError {
 	if stat.Code() == codes.OK {
 		return nil
 	}
 
 	details := stat.Proto().Details
-	msgs := make([]rpcResponseElement, len(details))
+	msgs := make([]RpcResponseElement, len(details))
 	for i, d := range details {
 		msgs[i] = responseToJSON(descSource, d)
 	}
-	return &rpcError{
+	return &RpcError{
 		Code:    uint32(stat.Code()),
 		Name:    stat.Code().String(),
 		Message: stat.Message(),
@@ -529,12 +757,12 @@ func toRpcError(descSource grpcurl.DescriptorSource, stat *status.Status) *rpcEr
 	}
 }
 
-func responseToJSON(descSource grpcurl.DescriptorSource, msg proto.Message) rpcResponseElement {
+func responseToJSON(descSource grpcurl.DescriptorSource, msg proto.Message) RpcResponseElement {
 	anyResolver := grpcurl.AnyResolverFromDescriptorSourceWithFallback(descSource)
 	jsm := jsonpb.Marshaler{EmitDefaults: true, OrigName: true, Indent: "  ", AnyResolver: anyResolver}
 	var b bytes.Buffer
 	if err := jsm.Marshal(&b, msg); err == nil {
-		return rpcResponseElement{Data: json.RawMessage(b.Bytes())}
+		return RpcResponseElement{Data: json.RawMessage(b.Bytes())}
 	} else {
 		b, err := json.Marshal(err.Error())
 		if err != nil {
@@ -542,6 +770,6 @@ func responseToJSON(descSource grpcurl.DescriptorSource, msg proto.Message) rpcR
 			// should never happen... here's a dumb fallback
 			b = []byte(strconv.Quote(err.Error()))
 		}
-		return rpcResponseElement{Data: b, IsError: true}
+		return RpcResponseElement{Data: b, IsError: true}
 	}
 }
@@ -0,0 +1,172 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/protobuf/proto"
+)
+
+// methodIndex maps the fully-qualified names of the methods of a host to
+// their descriptors, so invoking a method doesn't list and resolve every
+// service again. It is built once per Client and dropped with it, e.g. on
+// Reset.
+type methodIndex struct {
+	methods map[string]*desc.MethodDescriptor
+	// source holds the files of all methods, so RPCs resolve their message
+	// types locally instead of through reflection.
+	source grpcurl.DescriptorSource
+	// hash identifies the schema the index was built from.
+	hash string
+}
+
+func buildMethodIndex(source grpcurl.DescriptorSource) (*methodIndex, error) {
+	svcs, err := source.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	idx := &methodIndex{methods: map[string]*desc.MethodDescriptor{}}
+	var files []*desc.FileDescriptor
+	seen := map[string]bool{}
+	for _, svc := range svcs {
+		d, err := source.FindSymbol(svc)
+		if err != nil {
+			if hiddenServices[svc] {
+				// servers don't always have the descriptors of infrastructure services
+				continue
+			}
+			return nil, err
+		}
+		sd, ok := d.(*desc.ServiceDescriptor)
+		if !ok {
+			return nil, notService(svc, d)
+		}
+		for _, md := range sd.GetMethods() {
+			idx.methods[md.GetFullyQualifiedName()] = md
+		}
+		if fd := sd.GetFile(); !seen[fd.GetName()] {
+			seen[fd.GetName()] = true
+			files = append(files, fd)
+		}
+	}
+	if idx.hash, err = hashFiles(files); err != nil {
+		return nil, err
+	}
+	idx.source, err = grpcurl.DescriptorSourceFromFileDescriptors(files...)
+	if err != nil {
+		// files from reflection and from proto files may both define a
+		// dependency, resolve through the client then
+		idx.source = source
+	}
+	return idx, nil
+}
+
+// methodIndex returns the index of the methods of c, building it on first
+// use. A failed build is retried by the next caller.
+func (c *Client) methodIndex(ctx context.Context) (*methodIndex, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	idx, source := c.index, c.descSource
+	c.mu.Unlock()
+	if idx != nil {
+		return idx, nil
+	}
+	err := withContext(ctx, func() (err error) {
+		idx, err = buildMethodIndex(source)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.index == nil && c.descSource == source {
+		c.index = idx
+	}
+	return idx, nil
+}
+
+// minIndexRefresh is the least time between two refreshes of the index of a
+// Client, so that names that don't exist don't rescan the server every time.
+const minIndexRefresh = 5 * time.Second
+
+// refreshIndex fetches the descriptors of c from the server again. If they
+// changed, e.g. because the server was redeployed with new methods, they
+// replace the source and index of c. Within minIndexRefresh of the last
+// refresh, the current index is returned instead.
+func (c *Client) refreshIndex(ctx context.Context) (*methodIndex, error) {
+	if c.newSource == nil {
+		return c.methodIndex(ctx)
+	}
+	c.mu.Lock()
+	if time.Since(c.refreshed) < minIndexRefresh {
+		c.mu.Unlock()
+		return c.methodIndex(ctx)
+	}
+	c.refreshed = time.Now()
+	c.mu.Unlock()
+	refClient, source := c.newSource(c.ctx)
+	var idx *methodIndex
+	err := withContext(ctx, func() (err error) {
+		idx, err = buildMethodIndex(source)
+		return
+	})
+	if err != nil {
+		refClient.Reset()
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.index != nil && c.index.hash == idx.hash {
+		refClient.Reset()
+		return c.index, nil
+	}
+	// the previous reflection client may still be in use, it closes its
+	// stream once it is garbage collected
+	c.refClient, c.descSource, c.index = refClient, source, idx
+	return idx, nil
+}
+
+// hashFiles hashes files and their dependencies, so that a change of the
+// schema of a server can be told apart from a method that doesn't exist.
+func hashFiles(files []*desc.FileDescriptor) (string, error) {
+	all := map[string]*desc.FileDescriptor{}
+	var add func(fd *desc.FileDescriptor)
+	add = func(fd *desc.FileDescriptor) {
+		if _, ok := all[fd.GetName()]; ok {
+			return
+		}
+		all[fd.GetName()] = fd
+		for _, dep := range fd.GetDependencies() {
+			add(dep)
+		}
+	}
+	for _, fd := range files {
+		add(fd)
+	}
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	opts := proto.MarshalOptions{Deterministic: true}
+	for _, name := range names {
+		b, err := opts.Marshal(all[name].AsFileDescriptorProto())
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s:%d:", name, len(b))
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,115 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+)
+
+func TestMethodIndex(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr, Method: "user.User.GetUserList"}, r)
+	if _, err := ig.InvokeFunction(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	idx := registryClient(r, ig.G).index
+	if idx == nil {
+		t.Fatal("expected the index to be built on the first invoke")
+	}
+	for _, m := range []string{"user.User.Login", "user.User.WatchUsers", "user.User.Chat"} {
+		if _, ok := idx.methods[m]; !ok {
+			t.Errorf("expected %s to be indexed", m)
+		}
+	}
+	if _, err := idx.source.FindSymbol("user.LoginReq"); err != nil {
+		t.Fatalf("expected the index source to resolve messages: %v", err)
+	}
+
+	ig.G.Method = "user.User.Missing"
+	if _, err := ig.InvokeFunction(); err == nil {
+		t.Fatal("expected an error for an unknown method")
+	}
+	if registryClient(r, ig.G).index != idx {
+		t.Fatal("expected the index to be reused")
+	}
+
+	if err := ig.Reset(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if registryClient(r, ig.G).index != nil {
+		t.Fatal("expected Reset to drop the index")
+	}
+}
+
+// resolveMethodByScan resolves a method the way invocations did before the
+// method index: list and resolve every service, then scan their methods.
+func resolveMethodByScan(source grpcurl.DescriptorSource, host, method string) (*desc.MethodDescriptor, grpcurl.DescriptorSource, error) {
+	configs, err := ComputeSvcConfigs([]string{host}, []string{method})
+	if err != nil {
+		return nil, nil, err
+	}
+	descs, err := getMethods(source, configs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, md := range descs {
+		if md.GetFullyQualifiedName() == method {
+			s, err := grpcurl.DescriptorSourceFromFileDescriptors(md.GetFile())
+			return md, s, err
+		}
+	}
+	return nil, nil, fmt.Errorf("method %s not found", method)
+}
+
+func BenchmarkResolveMethod(b *testing.B) {
+	addr := startTestSvc(b)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	const method = "user.User.Login"
+	c, err := r.Get(context.Background(), &Grpc{Host: addr})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("scan", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, _, err := resolveMethodByScan(c.DescriptorSource(), addr, method); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("index", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			idx, err := c.methodIndex(context.Background())
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, ok := idx.methods[method]; !ok {
+				b.Fatal("method not found")
+			}
+		}
+	})
+}
+
+func BenchmarkInvokeFunction(b *testing.B) {
+	addr := startTestSvc(b)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	g := &Grpc{Host: addr, Method: "user.User.GetUserList"}
+	ig := NewInvokeGrpcWithRegistry(g, r)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		g.Body = strings.NewReader("{}")
+		if _, err := ig.InvokeFunction(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// MessageSource supplies the request messages of a client-streaming or bidi
+// RPC one at a time, returning io.EOF after the last one.
+type MessageSource func() (json.RawMessage, error)
+
+// Conversation scripts a bidi RPC in which a request depends on the response
+// to the previous one. It is called with nil for the first request and then
+// with each response as it arrives, and returns io.EOF to close the request
+// stream.
+type Conversation func(prev *RpcResponseElement) (json.RawMessage, error)
+
+// requestInput takes the request messages for md from r. Unary and
+// server-streaming methods get the whole body as their single message in
+// input.Data. Client-streaming and bidi methods get a source in hooks.next,
+// which reads Messages, Conversation or else the body, as a JSON array or as
+// a stream of JSON values such as NDJSON.
+func (r *Request) requestInput(ctx context.Context, md *desc.MethodDescriptor, input *rpcInput, hooks *invokeHooks) error {
+	switch {
+	case r.Conversation != nil:
+		if !md.IsClientStreaming() || !md.IsServerStreaming() {
+			return fmt.Errorf("a conversation needs a bidi streaming method, but %s is not", md.GetFullyQualifiedName())
+		}
+		hooks.next = r.conversationSource(ctx, hooks)
+	case r.Messages != nil:
+		hooks.next = r.Messages
+	case md.IsClientStreaming():
+		hooks.next = bodySource(r.Body)
+	default:
+		js, err := readBody(r.Body)
+		if err != nil {
+			return err
+		}
+		input.Data = append(input.Data, js)
+	}
+	if r.SendInterval > 0 && hooks.next != nil {
+		hooks.next = pacedSource(ctx, hooks.next, seconds(r.SendInterval))
+	}
+	return nil
+}
+
+func readBody(body io.Reader) (json.RawMessage, error) {
+	if body == nil {
+		return json.RawMessage("{}"), nil
+	}
+	return io.ReadAll(body)
+}
+
+// bodySource reads request messages from body, which holds either a JSON
+// array of messages or a sequence of JSON messages (e.g. NDJSON). Messages
+// are decoded as they are sent, so body may be a live stream.
+func bodySource(body io.Reader) MessageSource {
+	if body == nil {
+		return func() (json.RawMessage, error) {
+			return nil, io.EOF
+		}
+	}
+	br := bufio.NewReader(body)
+	dec := json.NewDecoder(br)
+	started, inArray := false, false
+	return func() (json.RawMessage, error) {
+		if !started {
+			started = true
+			c, err := peekNonSpace(br)
+			if err != nil {
+				return nil, err
+			}
+			if c == '[' {
+				if _, err := dec.Token(); err != nil {
+					return nil, err
+				}
+				inArray = true
+			}
+		}
+		if inArray && !dec.More() {
+			return nil, io.EOF
+		}
+		var msg json.RawMessage
+		if err := dec.Decode(&msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+}
+
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(c)) {
+			return c, br.UnreadByte()
+		}
+	}
+}
+
+// pacedSource waits interval between the messages supplied by next.
+func pacedSource(ctx context.Context, next func() (json.RawMessage, error), interval time.Duration) func() (json.RawMessage, error) {
+	first := true
+	return func() (json.RawMessage, error) {
+		if !first {
+			t := time.NewTimer(interval)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return nil, ctx.Err()
+			}
+		}
+		first = false
+		return next()
+	}
+}
+
+// conversationSource feeds the responses of the RPC back into
+// Conversation to produce the next request. It hooks into hooks to see
+// the responses, and ends the request stream once the server ends the RPC.
+func (r *Request) conversationSource(ctx context.Context, hooks *invokeHooks) MessageSource {
+	q := newResponseQueue()
+	onResponse := hooks.onResponse
+	hooks.onResponse = func(resp RpcResponseElement) bool {
+		if onResponse != nil && !onResponse(resp) {
+			return false
+		}
+		q.push(resp)
+		return true
+	}
+	onTrailers := hooks.onTrailers
+	hooks.onTrailers = func() {
+		q.close()
+		if onTrailers != nil {
+			onTrailers()
+		}
+	}
+
+	first := true
+	return func() (json.RawMessage, error) {
+		if first {
+			first = false
+			return r.Conversation(nil)
+		}
+		resp, ok := q.pop(ctx)
+		if !ok {
+			return nil, io.EOF
+		}
+		return r.Conversation(&resp)
+	}
+}
+
+// responseQueue hands responses from the receiving side of an RPC to the
+// sending side without ever blocking the receiver.
+type responseQueue struct {
+	mu     sync.Mutex
+	items  []RpcResponseElement
+	closed bool
+	signal chan struct{}
+}
+
+func newResponseQueue() *responseQueue {
+	return &responseQueue{signal: make(chan struct{}, 1)}
+}
+
+func (q *responseQueue) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (q *responseQueue) push(resp RpcResponseElement) {
+	q.mu.Lock()
+	if !q.closed {
+		q.items = append(q.items, resp)
+	}
+	q.mu.Unlock()
+	q.notify()
+}
+
+func (q *responseQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.notify()
+}
+
+// pop waits for the next response. It returns false once the queue is
+// closed and drained, or ctx is done.
+func (q *responseQueue) pop(ctx context.Context) (RpcResponseElement, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			resp := q.items[0]
+			q.items = q.items[1:]
+			q.mu.Unlock()
+			return resp, true
+		}
+		closed := q.closed
+		q.mu.Unlock()
+		if closed {
+			return RpcResponseElement{}, false
+		}
+		select {
+		case <-q.signal:
+		case <-ctx.Done():
+			return RpcResponseElement{}, false
+		}
+	}
+}
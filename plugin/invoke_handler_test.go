@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRPCInvokeHandler(t *testing.T) {
+	var mu sync.Mutex
+	var received metadata.MD
+	addr := startTestSvc(t, grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		mu.Lock()
+		received, _ = metadata.FromIncomingContext(ctx)
+		mu.Unlock()
+		return handler(ctx, req)
+	}))
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	md, _, err := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r).resolve(context.Background(), "user.User.RegisterUser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	h := RPCInvokeHandler(cc, md.GetService().GetMethods(), InvokeOptions{
+		ExtraMetadata:   []string{"token: extra"},
+		PreserveHeaders: []string{"X-Trace"},
+	})
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("X-Trace", "abc")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("/invoke/user.User.RegisterUser", fmt.Sprintf(`{"metadata":[{"name":"token","value":"form"},{"name":"id","value":"1"}],"data":[{"UserName":%q}]}`, uniqueName("invoke")))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body)
+	}
+	var results RpcResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if results.Error != nil || len(results.Responses) != 1 || results.Requests.Sent != 1 {
+		t.Fatalf("unexpected result %s", rec.Body)
+	}
+	mu.Lock()
+	token, id, trace := received.Get("token"), received.Get("id"), received.Get("x-trace")
+	mu.Unlock()
+	if len(token) != 1 || token[0] != "extra" || len(id) != 1 || id[0] != "1" || len(trace) != 1 || trace[0] != "abc" {
+		t.Fatalf("expected extra and preserved metadata to override the form, got %v", received)
+	}
+
+	if rec := post("/RegisterUsers", fmt.Sprintf(`{"data":[{"UserName":%q},{"UserName":%q}]}`, uniqueName("invoke"), uniqueName("invoke"))); rec.Code != http.StatusOK {
+		t.Fatalf("expected a short name under a stripped prefix to be invoked, got %d %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name, path, body string
+		code             int
+	}{
+		{"unknown method", "/invoke/user.User.Missing", `{"data":[{}]}`, http.StatusNotFound},
+		{"bad JSON", "/invoke/user.User.RegisterUser", `{"data":`, http.StatusBadRequest},
+		{"invalid message", "/invoke/user.User.RegisterUser", `{"data":[{"Sex":"Other"}]}`, http.StatusBadRequest},
+		{"prefix of the method name", "/invokeLogin", `{"data":[{}]}`, http.StatusNotFound},
+		{"too many messages", "/invoke/user.User.RegisterUser", `{"data":[{},{}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := post(tt.path, tt.body); rec.Code != tt.code {
+				t.Fatalf("expected %d, got %d %s", tt.code, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/invoke/user.User.RegisterUser", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected GET to be rejected, got %d", rec.Code)
+	}
+}
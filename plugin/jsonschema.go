@@ -0,0 +1,294 @@
+package plugin
+
+import (
+	"context"
+	"math"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// jsonSchemaDialect is the JSON Schema draft the exporter follows.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema (draft 2020-12) document or subschema.
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty"`
+	// Type is a single type name, or a list of them.
+	Type            interface{}            `json:"type,omitempty"`
+	Format          string                 `json:"format,omitempty"`
+	Pattern         string                 `json:"pattern,omitempty"`
+	ContentEncoding string                 `json:"contentEncoding,omitempty"`
+	Enum            []interface{}          `json:"enum,omitempty"`
+	Minimum         *int64                 `json:"minimum,omitempty"`
+	Maximum         *int64                 `json:"maximum,omitempty"`
+	Items           *JSONSchema            `json:"items,omitempty"`
+	Properties      map[string]*JSONSchema `json:"properties,omitempty"`
+	Required        []string               `json:"required,omitempty"`
+	PropertyNames   *JSONSchema            `json:"propertyNames,omitempty"`
+	// AdditionalProperties is the schema of the values of a map.
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	AllOf                []*JSONSchema          `json:"allOf,omitempty"`
+	AnyOf                []*JSONSchema          `json:"anyOf,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+	Not                  *JSONSchema            `json:"not,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// JSONSchemaOptions tune the JSON Schema of messages.
+type JSONSchemaOptions struct {
+	// UseProtoNames names properties after the fields in the proto files,
+	// instead of their lowerCamelCase JSON names. protojson accepts both.
+	UseProtoNames bool
+}
+
+// MessageJSONSchema returns the JSON Schema of md in the protojson mapping:
+// 64-bit integers are strings, enums are their value names, and the
+// well-known types have their special forms. Messages and enums are
+// defined in $defs, so recursive messages refer to themselves.
+func MessageJSONSchema(md *desc.MessageDescriptor, opts JSONSchemaOptions) *JSONSchema {
+	b := newJSONSchemaBuilder(opts, "#/$defs/")
+	s := b.message(md)
+	s.Schema = jsonSchemaDialect
+	if len(b.defs) > 0 {
+		s.Defs = b.defs
+	}
+	return s
+}
+
+// GetJSONSchema returns the JSON Schemas of the request and response types
+// of method, by any name Grpc.Method accepts.
+func (i *InvokeGrpc) GetJSONSchema(method string, opts JSONSchemaOptions) (request, response *JSONSchema, err error) {
+	return i.GetJSONSchemaContext(context.Background(), method, opts)
+}
+
+// GetJSONSchemaContext is like GetJSONSchema, but the reflection lookup is
+// bound to ctx.
+func (i *InvokeGrpc) GetJSONSchemaContext(ctx context.Context, method string, opts JSONSchemaOptions) (request, response *JSONSchema, err error) {
+	md, _, err := i.resolve(ctx, method)
+	if err != nil {
+		return nil, nil, err
+	}
+	return MessageJSONSchema(md.GetInputType(), opts), MessageJSONSchema(md.GetOutputType(), opts), nil
+}
+
+// jsonSchemaBuilder collects the definitions of the messages and enums it
+// visits, which are referred to by refPrefix followed by their full name.
+type jsonSchemaBuilder struct {
+	opts      JSONSchemaOptions
+	refPrefix string
+	defs      map[string]*JSONSchema
+}
+
+func newJSONSchemaBuilder(opts JSONSchemaOptions, refPrefix string) *jsonSchemaBuilder {
+	return &jsonSchemaBuilder{opts: opts, refPrefix: refPrefix, defs: map[string]*JSONSchema{}}
+}
+
+const (
+	int64Pattern  = `^-?[0-9]+$`
+	uint64Pattern = `^[0-9]+$`
+)
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+// wellKnownSchemas builds the schemas of the well-known types whose JSON
+// form isn't that of a regular message.
+var wellKnownSchemas = map[string]func() *JSONSchema{
+	"google.protobuf.Timestamp": func() *JSONSchema {
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	},
+	"google.protobuf.Duration": func() *JSONSchema {
+		return &JSONSchema{Type: "string", Pattern: `^-?[0-9]+(\.[0-9]{1,9})?s$`}
+	},
+	"google.protobuf.FieldMask": func() *JSONSchema {
+		return &JSONSchema{Type: "string"}
+	},
+	"google.protobuf.Struct": func() *JSONSchema {
+		return &JSONSchema{Type: "object"}
+	},
+	"google.protobuf.Value": func() *JSONSchema {
+		return &JSONSchema{}
+	},
+	"google.protobuf.ListValue": func() *JSONSchema {
+		return &JSONSchema{Type: "array"}
+	},
+	"google.protobuf.Any": func() *JSONSchema {
+		return &JSONSchema{
+			Type:       "object",
+			Properties: map[string]*JSONSchema{"@type": {Type: "string"}},
+			Required:   []string{"@type"},
+		}
+	},
+	"google.protobuf.DoubleValue": func() *JSONSchema { return scalarSchema(descriptor.FieldDescriptorProto_TYPE_DOUBLE) },
+	"google.protobuf.FloatValue":  func() *JSONSchema { return scalarSchema(descriptor.FieldDescriptorProto_TYPE_FLOAT) },
+	"google.protobuf.Int64Value":  func() *JSONSchema { return scalarSchema(descriptor.FieldDescriptorProto_TYPE_INT64) },
+	"google.protobuf.UInt64Value": func() *JSONSchema { return scalarSchema(descriptor.FieldDescriptorProto_TYPE_UINT64) },
+	"google.protobuf.Int32Value":  func() *JSONSchema { return scalarSchema(descriptor.FieldDescriptorProto_TYPE_INT32) },
+	"google.protobuf.UInt32Value": func() *JSONSchema { return scalarSchema(descriptor.FieldDescriptorProto_TYPE_UINT32) },
+	"google.protobuf.BoolValue":   func() *JSONSchema { return scalarSchema(descriptor.FieldDescriptorProto_TYPE_BOOL) },
+	"google.protobuf.StringValue": func() *JSONSchema { return scalarSchema(descriptor.FieldDescriptorProto_TYPE_STRING) },
+	"google.protobuf.BytesValue":  func() *JSONSchema { return scalarSchema(descriptor.FieldDescriptorProto_TYPE_BYTES) },
+}
+
+// scalarSchema is the schema of the scalar type t.
+func scalarSchema(t descriptor.FieldDescriptorProto_Type) *JSONSchema {
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32, descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return &JSONSchema{Type: "integer", Format: "int32", Minimum: int64Ptr(math.MinInt32), Maximum: int64Ptr(math.MaxInt32)}
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return &JSONSchema{Type: "integer", Format: "uint32", Minimum: int64Ptr(0), Maximum: int64Ptr(math.MaxUint32)}
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return &JSONSchema{Type: "string", Format: "int64", Pattern: int64Pattern}
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return &JSONSchema{Type: "string", Format: "uint64", Pattern: uint64Pattern}
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return &JSONSchema{Type: "number", Format: "float"}
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return &JSONSchema{Type: "number", Format: "double"}
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return &JSONSchema{Type: "boolean"}
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return &JSONSchema{Type: "string", ContentEncoding: "base64"}
+	default:
+		return &JSONSchema{Type: "string"}
+	}
+}
+
+// message returns the schema of md: the schema of a well-known type, or a
+// reference to the definition of md.
+func (b *jsonSchemaBuilder) message(md *desc.MessageDescriptor) *JSONSchema {
+	name := md.GetFullyQualifiedName()
+	if wk, ok := wellKnownSchemas[name]; ok {
+		s := wk()
+		s.Title = name
+		return s
+	}
+	if _, ok := b.defs[name]; !ok {
+		def := &JSONSchema{Title: name}
+		// defined before the fields are, so recursive fields find it
+		b.defs[name] = def
+		b.fillMessage(def, md)
+	}
+	return &JSONSchema{Ref: b.refPrefix + name}
+}
+
+func (b *jsonSchemaBuilder) fillMessage(s *JSONSchema, md *desc.MessageDescriptor) {
+	s.Type = "object"
+	s.Description = prettify(md.GetSourceInfo().GetLeadingComments())
+	s.Deprecated = md.GetMessageOptions().GetDeprecated()
+	// other properties are left open: protojson accepts the proto names of
+	// the fields as well as their JSON names
+	s.Properties = map[string]*JSONSchema{}
+	for _, fd := range md.GetFields() {
+		s.Properties[b.fieldName(fd)] = b.field(fd)
+		if fd.IsRequired() {
+			s.Required = append(s.Required, b.fieldName(fd))
+		}
+	}
+	for _, ood := range md.GetOneOfs() {
+		if ood.IsSynthetic() {
+			// proto3 optional fields may simply be left out
+			continue
+		}
+		s.AllOf = append(s.AllOf, b.oneOf(ood))
+	}
+}
+
+// oneOf allows at most one of the fields of ood to be set: either exactly
+// one of them is, or none.
+func (b *jsonSchemaBuilder) oneOf(ood *desc.OneOfDescriptor) *JSONSchema {
+	s := &JSONSchema{}
+	var none []*JSONSchema
+	for _, fd := range ood.GetChoices() {
+		set := &JSONSchema{Required: []string{b.fieldName(fd)}}
+		s.OneOf = append(s.OneOf, set)
+		none = append(none, set)
+	}
+	s.OneOf = append(s.OneOf, &JSONSchema{Not: &JSONSchema{AnyOf: none}})
+	return s
+}
+
+func (b *jsonSchemaBuilder) fieldName(fd *desc.FieldDescriptor) string {
+	if b.opts.UseProtoNames {
+		return fd.GetName()
+	}
+	return fd.GetJSONName()
+}
+
+func (b *jsonSchemaBuilder) field(fd *desc.FieldDescriptor) *JSONSchema {
+	var s *JSONSchema
+	switch {
+	case fd.IsMap():
+		s = &JSONSchema{
+			Type:                 "object",
+			PropertyNames:        mapKeySchema(fd.GetMapKeyType()),
+			AdditionalProperties: b.singular(fd.GetMapValueType()),
+		}
+	case fd.IsRepeated():
+		s = &JSONSchema{Type: "array", Items: b.singular(fd)}
+	default:
+		s = b.singular(fd)
+	}
+	if c := prettify(fd.GetSourceInfo().GetLeadingComments()); c != "" || fd.GetFieldOptions().GetDeprecated() {
+		if s.Ref != "" {
+			// keep the referenced definition as is
+			s = &JSONSchema{AllOf: []*JSONSchema{s}}
+		}
+		s.Description = c
+		s.Deprecated = fd.GetFieldOptions().GetDeprecated()
+	}
+	return s
+}
+
+// singular is the schema of a single value of fd, ignoring its label.
+func (b *jsonSchemaBuilder) singular(fd *desc.FieldDescriptor) *JSONSchema {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return b.message(fd.GetMessageType())
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		return b.enum(fd.GetEnumType())
+	default:
+		return scalarSchema(fd.GetType())
+	}
+}
+
+func (b *jsonSchemaBuilder) enum(ed *desc.EnumDescriptor) *JSONSchema {
+	name := ed.GetFullyQualifiedName()
+	if name == "google.protobuf.NullValue" {
+		return &JSONSchema{Type: "null"}
+	}
+	if _, ok := b.defs[name]; !ok {
+		def := &JSONSchema{
+			Title:       name,
+			Description: prettify(ed.GetSourceInfo().GetLeadingComments()),
+			Type:        "string",
+		}
+		for _, evd := range ed.GetValues() {
+			def.Enum = append(def.Enum, evd.GetName())
+		}
+		b.defs[name] = def
+	}
+	return &JSONSchema{Ref: b.refPrefix + name}
+}
+
+// mapKeySchema constrains the keys of a map, which JSON always has as
+// strings, to the values of their proto type.
+func mapKeySchema(fd *desc.FieldDescriptor) *JSONSchema {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return nil
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return &JSONSchema{Enum: []interface{}{"true", "false"}}
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return &JSONSchema{Pattern: uint64Pattern}
+	default:
+		return &JSONSchema{Pattern: int64Pattern}
+	}
+}
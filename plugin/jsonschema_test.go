@@ -0,0 +1,154 @@
+package plugin
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+const schemaProto = `syntax = "proto3";
+package schema;
+
+import "google/protobuf/any.proto";
+import "google/protobuf/duration.proto";
+import "google/protobuf/struct.proto";
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+enum Color {
+  COLOR_UNSPECIFIED = 0;
+  RED = 1;
+}
+
+// Node is a tree.
+message Node {
+  // id identifies the node.
+  int64 id = 1;
+  uint32 weight = 2;
+  bytes data = 3;
+  // color is red.
+  Color color = 4;
+  repeated Node children = 5;
+  map<int32, string> labels = 6;
+  oneof value {
+    string text = 7;
+    double number = 8;
+  }
+  optional bool flag = 9;
+  google.protobuf.Timestamp created = 10;
+  google.protobuf.Duration ttl = 11;
+  google.protobuf.Struct attrs = 12;
+  google.protobuf.Int64Value count = 13;
+  google.protobuf.Any detail = 14;
+  string old_name = 15 [deprecated = true];
+}
+`
+
+func parseSchemaProto(t *testing.T) *desc.FileDescriptor {
+	return parseTestProto(t, "schema.proto", map[string]string{"schema.proto": schemaProto})
+}
+
+// jsonValue marshals v and decodes it again, to compare with JSON literals.
+func jsonValue(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestMessageJSONSchema(t *testing.T) {
+	fd := parseSchemaProto(t)
+	s := MessageJSONSchema(fd.FindMessage("schema.Node"), JSONSchemaOptions{})
+	if s.Schema != jsonSchemaDialect || s.Ref != "#/$defs/schema.Node" {
+		t.Fatalf("unexpected root: %+v", s)
+	}
+	if len(s.Defs) != 2 {
+		t.Fatalf("expected Node and Color to be defined, got %v", s.Defs)
+	}
+	node := s.Defs["schema.Node"]
+	if node.Description != "Node is a tree." {
+		t.Fatalf("unexpected message schema: %+v", node)
+	}
+	if _, ok := jsonValue(t, node).(map[string]interface{})["additionalProperties"]; ok {
+		t.Fatal("expected the proto names of fields to be allowed too")
+	}
+
+	tests := []struct {
+		prop string
+		want string
+	}{
+		{"id", `{"type":"string","format":"int64","pattern":"^-?[0-9]+$","description":"id identifies the node."}`},
+		{"weight", `{"type":"integer","format":"uint32","minimum":0,"maximum":4294967295}`},
+		{"data", `{"type":"string","contentEncoding":"base64"}`},
+		{"color", `{"allOf":[{"$ref":"#/$defs/schema.Color"}],"description":"color is red."}`},
+		{"children", `{"type":"array","items":{"$ref":"#/$defs/schema.Node"}}`},
+		{"labels", `{"type":"object","propertyNames":{"pattern":"^-?[0-9]+$"},"additionalProperties":{"type":"string"}}`},
+		{"number", `{"type":"number","format":"double"}`},
+		{"flag", `{"type":"boolean"}`},
+		{"created", `{"title":"google.protobuf.Timestamp","type":"string","format":"date-time"}`},
+		{"ttl", `{"title":"google.protobuf.Duration","type":"string","pattern":"^-?[0-9]+(\\.[0-9]{1,9})?s$"}`},
+		{"attrs", `{"title":"google.protobuf.Struct","type":"object"}`},
+		{"count", `{"title":"google.protobuf.Int64Value","type":"string","format":"int64","pattern":"^-?[0-9]+$"}`},
+		{"detail", `{"title":"google.protobuf.Any","type":"object","properties":{"@type":{"type":"string"}},"required":["@type"]}`},
+		{"oldName", `{"type":"string","deprecated":true}`},
+	}
+	for _, tt := range tests {
+		var want interface{}
+		if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+			t.Fatal(err)
+		}
+		if got := jsonValue(t, node.Properties[tt.prop]); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", tt.prop, got, want)
+		}
+	}
+	if len(node.Properties) != 15 {
+		t.Errorf("expected 15 properties, got %d", len(node.Properties))
+	}
+
+	oneOf := `[{"oneOf":[{"required":["text"]},{"required":["number"]},{"not":{"anyOf":[{"required":["text"]},{"required":["number"]}]}}]}]`
+	var want interface{}
+	if err := json.Unmarshal([]byte(oneOf), &want); err != nil {
+		t.Fatal(err)
+	}
+	if got := jsonValue(t, node.AllOf); !reflect.DeepEqual(got, want) {
+		t.Errorf("oneof: got %v, want %v", got, want)
+	}
+	if got := s.Defs["schema.Color"].Enum; !reflect.DeepEqual(got, []interface{}{"COLOR_UNSPECIFIED", "RED"}) {
+		t.Errorf("unexpected enum values %v", got)
+	}
+
+	s = MessageJSONSchema(fd.FindMessage("schema.Node"), JSONSchemaOptions{UseProtoNames: true})
+	if _, ok := s.Defs["schema.Node"].Properties["old_name"]; !ok {
+		t.Error("expected properties to be named after the proto fields")
+	}
+}
+
+func TestGetJSONSchema(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r)
+	req, resp, err := ig.GetJSONSchema("GetUserList", JSONSchemaOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Ref != "#/$defs/user.GetUserListReq" || resp.Ref != "#/$defs/user.GetUserListResp" {
+		t.Fatalf("unexpected schemas %s, %s", req.Ref, resp.Ref)
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"$schema":"https://json-schema.org/draft/2020-12/schema"`) {
+		t.Fatalf("expected the dialect to be declared: %s", b)
+	}
+}
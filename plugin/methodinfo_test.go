@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/test-instructor/grpc-plugin/demo"
+)
+
+func TestGetMethodInfo(t *testing.T) {
+	addr := serveTestSvc(t, demo.NewUserSvc())
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	g := &Grpc{Host: addr, ImportPaths: []string{testProtoDir}, ProtoFiles: []string{"user.proto"}, DisableReflection: true}
+	ig := NewInvokeGrpcWithRegistry(g, r)
+
+	info, err := ig.GetMethodInfo("User/WatchUsers")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.FullName != "user.User.WatchUsers" || info.RequestType != "user.WatchUsersReq" || info.ResponseType != "user.WatchUsersResp" {
+		t.Fatalf("unexpected names: %+v", info)
+	}
+	if info.RequestStream || !info.ResponseStream {
+		t.Fatalf("expected a server-streaming method, got %+v", info)
+	}
+	if !strings.Contains(info.LeadingComments, "WatchUsers streams a message") {
+		t.Fatalf("expected the comments from source info, got %q", info.LeadingComments)
+	}
+	if info.Deprecated || info.IdempotencyLevel != "IDEMPOTENCY_UNKNOWN" {
+		t.Fatalf("unexpected options: %+v", info)
+	}
+	for _, msg := range []string{info.RequestType, info.ResponseType} {
+		if _, ok := info.MessageTypes[msg]; !ok {
+			t.Errorf("expected the schema of %s", msg)
+		}
+	}
+	if info.Body == "" {
+		t.Fatal("expected a request body template")
+	}
+
+	if _, err := ig.GetMethodInfo("WatchUser"); err == nil {
+		t.Fatal("expected an error for an unknown method")
+	}
+}
+
+const optionsProto = `syntax = "proto3";
+package opts;
+
+message Req {}
+message Resp { string value = 1; }
+
+service Svc {
+  // Get is safe to retry.
+  rpc Get (Req) returns (Resp) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+  rpc Old (Req) returns (Resp) { // use Get
+    option deprecated = true;
+  }
+}
+`
+
+func parseOptionsProto(t *testing.T) *desc.FileDescriptor {
+	return parseTestProto(t, "opts.proto", map[string]string{"opts.proto": optionsProto})
+}
+
+func TestRPCMetadataHandler(t *testing.T) {
+	fd := parseOptionsProto(t)
+	svc := fd.FindService("opts.Svc")
+	h := RPCMetadataHandler(svc.GetMethods(), []*desc.FileDescriptor{fd})
+
+	tests := []struct {
+		method string
+		want   schema
+	}{
+		{"opts.Svc.Get", schema{
+			FullName:         "opts.Svc.Get",
+			RequestType:      "opts.Req",
+			ResponseType:     "opts.Resp",
+			LeadingComments:  " Get is safe to retry.\n",
+			IdempotencyLevel: "NO_SIDE_EFFECTS",
+		}},
+		{"Svc/Old", schema{
+			FullName:         "opts.Svc.Old",
+			RequestType:      "opts.Req",
+			ResponseType:     "opts.Resp",
+			TrailingComments: " use Get\n",
+			Deprecated:       true,
+			IdempotencyLevel: "IDEMPOTENCY_UNKNOWN",
+		}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/?method="+tt.method, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d: %s", tt.method, rec.Code, rec.Body)
+		}
+		var got schema
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := got.MessageTypes["opts.Resp"]; !ok {
+			t.Errorf("%s: expected the response schema", tt.method)
+		}
+		got.MessageTypes, got.EnumTypes = nil, nil
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.method, got, tt.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/?method=opts.Svc.Missing", nil))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected an unknown method to be rejected, got %d", rec.Code)
+	}
+}
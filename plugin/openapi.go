@@ -0,0 +1,383 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+)
+
+// openAPIVersion is the OpenAPI version of the generated documents, the
+// first to use JSON Schema draft 2020-12 for its schemas.
+const openAPIVersion = "3.1.0"
+
+// OpenAPIDocument is an OpenAPI document describing gRPC methods as HTTP
+// operations.
+type OpenAPIDocument struct {
+	OpenAPI    string                      `json:"openapi"`
+	Info       OpenAPIInfo                 `json:"info"`
+	Tags       []OpenAPITag                `json:"tags,omitempty"`
+	Paths      map[string]*OpenAPIPathItem `json:"paths"`
+	Components OpenAPIComponents           `json:"components"`
+	// UnsupportedRules are the HTTP rules left out of the document.
+	UnsupportedRules []OpenAPIUnsupportedRule `json:"x-unsupported-rules,omitempty"`
+}
+
+// OpenAPIUnsupportedRule is an HTTP rule OpenAPI can't describe.
+type OpenAPIUnsupportedRule struct {
+	OperationID string `json:"operationId"`
+	Reason      string `json:"reason"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenAPITag groups the operations of a service.
+type OpenAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIPathItem struct {
+	Get     *OpenAPIOperation `json:"get,omitempty"`
+	Put     *OpenAPIOperation `json:"put,omitempty"`
+	Post    *OpenAPIOperation `json:"post,omitempty"`
+	Delete  *OpenAPIOperation `json:"delete,omitempty"`
+	Options *OpenAPIOperation `json:"options,omitempty"`
+	Head    *OpenAPIOperation `json:"head,omitempty"`
+	Patch   *OpenAPIOperation `json:"patch,omitempty"`
+	Trace   *OpenAPIOperation `json:"trace,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required,omitempty"`
+	Schema   *JSONSchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *JSONSchema `json:"schema"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas,omitempty"`
+}
+
+// OpenAPIOptions tune the generated OpenAPI documents.
+type OpenAPIOptions struct {
+	// Title and Version describe the document, they default to "gRPC
+	// services" and "1.0.0".
+	Title   string
+	Version string
+	// JSONSchema tunes the schemas of the messages.
+	JSONSchema JSONSchemaOptions
+}
+
+const (
+	jsonContentType = "application/json"
+	// statusSchemaName is the component describing the errors of methods.
+	statusSchemaName = "google.rpc.Status"
+)
+
+// NewOpenAPIDocument describes methods as HTTP operations. A method
+// annotated with google.api.http gets the operations of its HTTP rule and
+// additional bindings, any other method a POST operation on its gRPC path,
+// e.g. /user.User/Login, taking the request message as its body. The
+// requests of client-streaming methods and the responses of
+// server-streaming ones are arrays of messages, as InvokeGrpc takes and
+// returns them.
+//
+// Rules that OpenAPI can't describe, custom patterns of other verbs than
+// its own and operations bound to the same path and verb as another, are
+// left out and listed in UnsupportedRules.
+func NewOpenAPIDocument(methods []*desc.MethodDescriptor, opts OpenAPIOptions) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    OpenAPIInfo{Title: opts.Title, Version: opts.Version},
+		Paths:   map[string]*OpenAPIPathItem{},
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "gRPC services"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "1.0.0"
+	}
+
+	b := newJSONSchemaBuilder(opts.JSONSchema, "#/components/schemas/")
+	tagged := map[string]bool{}
+	for _, md := range methods {
+		sd := md.GetService()
+		if svc := sd.GetFullyQualifiedName(); !tagged[svc] {
+			tagged[svc] = true
+			doc.Tags = append(doc.Tags, OpenAPITag{Name: svc, Description: prettify(sd.GetSourceInfo().GetLeadingComments())})
+		}
+
+		rules := httpRules(md)
+		if len(rules) == 0 {
+			op := b.operation(md, md.GetFullyQualifiedName())
+			op.RequestBody = requestBody(b.streamSchema(md.GetInputType(), md.IsClientStreaming()))
+			doc.addOperation("POST", "/"+sd.GetFullyQualifiedName()+"/"+md.GetName(), op)
+			continue
+		}
+		for n, rule := range rules {
+			id := md.GetFullyQualifiedName()
+			if n > 0 {
+				id = fmt.Sprintf("%s_%d", id, n)
+			}
+			b.addRule(doc, md, rule, id)
+		}
+	}
+	b.defs[statusSchemaName] = statusSchema()
+	doc.Components.Schemas = b.defs
+	return doc
+}
+
+// GetOpenAPI describes the services of the host of G, see
+// NewOpenAPIDocument. The title defaults to the host.
+func (i *InvokeGrpc) GetOpenAPI(opts OpenAPIOptions) (*OpenAPIDocument, error) {
+	return i.GetOpenAPIContext(context.Background(), opts)
+}
+
+// GetOpenAPIContext is like GetOpenAPI, but the reflection lookups are
+// bound to ctx.
+func (i *InvokeGrpc) GetOpenAPIContext(ctx context.Context, opts OpenAPIOptions) (*OpenAPIDocument, error) {
+	svcs, err := i.GetSvsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var methods []*desc.MethodDescriptor
+	for _, svc := range svcs {
+		d, _, err := i.findSymbol(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		sd, ok := d.(*desc.ServiceDescriptor)
+		if !ok {
+			return nil, notService(svc, d)
+		}
+		methods = append(methods, sd.GetMethods()...)
+	}
+	if opts.Title == "" {
+		opts.Title = i.G.Host
+	}
+	return NewOpenAPIDocument(methods, opts), nil
+}
+
+// addOperation adds op to path for the HTTP method verb. An operation
+// OpenAPI can't hold is listed in UnsupportedRules instead.
+func (doc *OpenAPIDocument) addOperation(verb, path string, op *OpenAPIOperation) {
+	item := doc.Paths[path]
+	if item == nil {
+		item = &OpenAPIPathItem{}
+	}
+	var reason string
+	switch slot := item.operation(verb); {
+	case slot == nil:
+		reason = fmt.Sprintf("HTTP method %q is not supported by OpenAPI", verb)
+	case *slot != nil:
+		reason = fmt.Sprintf("%s %s is already bound to %s", verb, path, (*slot).OperationID)
+	default:
+		*slot = op
+		doc.Paths[path] = item
+		return
+	}
+	doc.UnsupportedRules = append(doc.UnsupportedRules, OpenAPIUnsupportedRule{OperationID: op.OperationID, Reason: reason})
+}
+
+// operation returns the field of the operation for the HTTP method verb,
+// or nil if OpenAPI has none.
+func (item *OpenAPIPathItem) operation(verb string) **OpenAPIOperation {
+	switch strings.ToUpper(verb) {
+	case "GET":
+		return &item.Get
+	case "PUT":
+		return &item.Put
+	case "POST":
+		return &item.Post
+	case "DELETE":
+		return &item.Delete
+	case "OPTIONS":
+		return &item.Options
+	case "HEAD":
+		return &item.Head
+	case "PATCH":
+		return &item.Patch
+	case "TRACE":
+		return &item.Trace
+	}
+	return nil
+}
+
+// operation describes md, responding with its response message.
+func (b *jsonSchemaBuilder) operation(md *desc.MethodDescriptor, id string) *OpenAPIOperation {
+	return &OpenAPIOperation{
+		OperationID: id,
+		Description: prettify(md.GetSourceInfo().GetLeadingComments()),
+		Tags:        []string{md.GetService().GetFullyQualifiedName()},
+		Deprecated:  md.GetMethodOptions().GetDeprecated(),
+		Responses: map[string]*OpenAPIResponse{
+			"200": {
+				Description: "OK",
+				Content:     map[string]OpenAPIMediaType{jsonContentType: {Schema: b.streamSchema(md.GetOutputType(), md.IsServerStreaming())}},
+			},
+			"default": {
+				Description: "The status of a failed call",
+				Content:     map[string]OpenAPIMediaType{jsonContentType: {Schema: &JSONSchema{Ref: b.refPrefix + statusSchemaName}}},
+			},
+		},
+	}
+}
+
+// streamSchema is the schema of md, or of an array of md for a stream.
+func (b *jsonSchemaBuilder) streamSchema(md *desc.MessageDescriptor, stream bool) *JSONSchema {
+	s := b.message(md)
+	if stream {
+		return &JSONSchema{Type: "array", Items: s}
+	}
+	return s
+}
+
+func requestBody(s *JSONSchema) *OpenAPIRequestBody {
+	return &OpenAPIRequestBody{Required: true, Content: map[string]OpenAPIMediaType{jsonContentType: {Schema: s}}}
+}
+
+// httpRules returns the google.api.http rule of md and its additional
+// bindings, if md has one.
+func httpRules(md *desc.MethodDescriptor) []*annotations.HttpRule {
+	opts := md.GetMethodOptions()
+	if opts == nil {
+		return nil
+	}
+	// descriptors from reflection or proto files may keep the extension as
+	// an unknown field, parsing the options again resolves it
+	b, err := proto.Marshal(opts)
+	if err != nil {
+		return nil
+	}
+	var parsed descriptor.MethodOptions
+	if err := proto.Unmarshal(b, &parsed); err != nil {
+		return nil
+	}
+	rule, ok := proto.GetExtension(&parsed, annotations.E_Http).(*annotations.HttpRule)
+	if !ok || rule == nil {
+		return nil
+	}
+	return append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...)
+}
+
+// pathVariable matches the variables of path templates, e.g. {name=shelves/*}.
+var pathVariable = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+
+func (b *jsonSchemaBuilder) addRule(doc *OpenAPIDocument, md *desc.MethodDescriptor, rule *annotations.HttpRule, id string) {
+	var verb, path string
+	switch {
+	case rule.GetGet() != "":
+		verb, path = "GET", rule.GetGet()
+	case rule.GetPut() != "":
+		verb, path = "PUT", rule.GetPut()
+	case rule.GetPost() != "":
+		verb, path = "POST", rule.GetPost()
+	case rule.GetDelete() != "":
+		verb, path = "DELETE", rule.GetDelete()
+	case rule.GetPatch() != "":
+		verb, path = "PATCH", rule.GetPatch()
+	case rule.GetCustom() != nil:
+		verb, path = rule.GetCustom().GetKind(), rule.GetCustom().GetPath()
+	}
+
+	op := b.operation(md, id)
+	in := md.GetInputType()
+	bound := map[string]bool{}
+	for _, m := range pathVariable.FindAllStringSubmatch(path, -1) {
+		field := m[1]
+		bound[strings.SplitN(field, ".", 2)[0]] = true
+		s := &JSONSchema{Type: "string"}
+		if fd := findFieldPath(in, field); fd != nil {
+			s = b.field(fd)
+		}
+		op.Parameters = append(op.Parameters, OpenAPIParameter{Name: field, In: "path", Required: true, Schema: s})
+	}
+	switch body := rule.GetBody(); body {
+	case "*":
+		op.RequestBody = requestBody(b.streamSchema(in, md.IsClientStreaming()))
+	case "":
+		// the fields not bound by the path are query parameters
+		for _, fd := range in.GetFields() {
+			if bound[fd.GetName()] || fd.IsMap() || fd.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE || fd.GetType() == descriptor.FieldDescriptorProto_TYPE_GROUP {
+				continue
+			}
+			op.Parameters = append(op.Parameters, OpenAPIParameter{Name: b.fieldName(fd), In: "query", Schema: b.field(fd)})
+		}
+	default:
+		if fd := in.FindFieldByName(body); fd != nil {
+			op.RequestBody = requestBody(b.field(fd))
+		}
+	}
+	if rb := rule.GetResponseBody(); rb != "" {
+		if fd := md.GetOutputType().FindFieldByName(rb); fd != nil {
+			op.Responses["200"].Content[jsonContentType] = OpenAPIMediaType{Schema: b.field(fd)}
+		}
+	}
+
+	doc.addOperation(verb, pathVariable.ReplaceAllString(path, "{$1}"), op)
+}
+
+// findFieldPath finds a field of md by its dotted path, e.g. book.name.
+func findFieldPath(md *desc.MessageDescriptor, path string) *desc.FieldDescriptor {
+	parts := strings.Split(path, ".")
+	for i, name := range parts {
+		fd := md.FindFieldByName(name)
+		if fd == nil {
+			return nil
+		}
+		if i == len(parts)-1 {
+			return fd
+		}
+		if md = fd.GetMessageType(); md == nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// statusSchema describes google.rpc.Status, as the details of failed calls
+// are reported in RpcResult.
+func statusSchema() *JSONSchema {
+	return &JSONSchema{
+		Title: statusSchemaName,
+		Type:  "object",
+		Properties: map[string]*JSONSchema{
+			"code":    scalarSchema(descriptor.FieldDescriptorProto_TYPE_INT32),
+			"message": {Type: "string"},
+			"details": {Type: "array", Items: wellKnownSchemas["google.protobuf.Any"]()},
+		},
+	}
+}
@@ -0,0 +1,263 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// googleAPIProtos are the parts of google/api/http.proto and
+// annotations.proto the test uses.
+var googleAPIProtos = map[string]string{
+	"google/api/http.proto": `syntax = "proto3";
+package google.api;
+
+message HttpRule {
+  string selector = 1;
+  oneof pattern {
+    string get = 2;
+    string put = 3;
+    string post = 4;
+    string delete = 5;
+    string patch = 6;
+    CustomHttpPattern custom = 8;
+  }
+  string body = 7;
+  string response_body = 12;
+  repeated HttpRule additional_bindings = 11;
+}
+
+message CustomHttpPattern {
+  string kind = 1;
+  string path = 2;
+}
+`,
+	"google/api/annotations.proto": `syntax = "proto3";
+package google.api;
+
+import "google/api/http.proto";
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.MethodOptions {
+  HttpRule http = 72295728;
+}
+`,
+}
+
+const libraryProto = `syntax = "proto3";
+package library;
+
+import "google/api/annotations.proto";
+
+message Book {
+  string name = 1;
+  string title = 2;
+  int64 pages = 3;
+}
+
+message GetBookReq {
+  string name = 1;
+  bool full = 2;
+  Book filter = 3;
+}
+
+message UpdateBookReq {
+  Book book = 1;
+}
+
+message ListBooksResp {
+  repeated Book books = 1;
+}
+
+// Library lends books.
+service Library {
+  // GetBook returns a book.
+  rpc GetBook (GetBookReq) returns (Book) {
+    option (google.api.http) = {
+      get: "/v1/{name=books/*}"
+      additional_bindings { post: "/v1/books:get" body: "*" }
+    };
+  }
+  rpc UpdateBook (UpdateBookReq) returns (Book) {
+    option (google.api.http) = { patch: "/v1/{book.name=books/*}" body: "book" };
+  }
+  rpc ListBooks (GetBookReq) returns (ListBooksResp) {
+    option (google.api.http) = { get: "/v1/books" response_body: "books" };
+  }
+  rpc WatchBooks (GetBookReq) returns (stream Book) {}
+}
+`
+
+func parseLibraryProto(t *testing.T) *desc.FileDescriptor {
+	files := map[string]string{"library.proto": libraryProto}
+	for name, src := range googleAPIProtos {
+		files[name] = src
+	}
+	return parseTestProto(t, "library.proto", files)
+}
+
+func TestNewOpenAPIDocument(t *testing.T) {
+	fd := parseLibraryProto(t)
+	doc := NewOpenAPIDocument(fd.FindService("library.Library").GetMethods(), OpenAPIOptions{})
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title == "" || doc.Info.Version == "" {
+		t.Fatalf("unexpected document header: %+v %+v", doc.OpenAPI, doc.Info)
+	}
+	if !reflect.DeepEqual(doc.Tags, []OpenAPITag{{Name: "library.Library", Description: "Library lends books."}}) {
+		t.Fatalf("unexpected tags %+v", doc.Tags)
+	}
+
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	for _, path := range []string{"/v1/{name}", "/v1/books:get", "/v1/{book.name}", "/v1/books", "/library.Library/WatchBooks"} {
+		if doc.Paths[path] == nil {
+			t.Fatalf("expected path %s, got %v", path, paths)
+		}
+	}
+
+	get := doc.Paths["/v1/{name}"].Get
+	if get == nil || get.OperationID != "library.Library.GetBook" || get.Description != "GetBook returns a book." {
+		t.Fatalf("unexpected GET operation %+v", get)
+	}
+	wantParams := []OpenAPIParameter{
+		{Name: "name", In: "path", Required: true, Schema: &JSONSchema{Type: "string"}},
+		{Name: "full", In: "query", Schema: &JSONSchema{Type: "boolean"}},
+	}
+	if !reflect.DeepEqual(get.Parameters, wantParams) || get.RequestBody != nil {
+		t.Fatalf("unexpected parameters %+v", get.Parameters)
+	}
+	if ref := get.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/library.Book" {
+		t.Fatalf("unexpected response %s", ref)
+	}
+
+	post := doc.Paths["/v1/books:get"].Post
+	if post == nil || post.OperationID != "library.Library.GetBook_1" || post.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/library.GetBookReq" {
+		t.Fatalf("unexpected additional binding %+v", post)
+	}
+
+	patch := doc.Paths["/v1/{book.name}"].Patch
+	if patch == nil || patch.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/library.Book" {
+		t.Fatalf("expected the book field as the body, got %+v", patch)
+	}
+
+	list := doc.Paths["/v1/books"].Get.Responses["200"].Content["application/json"].Schema
+	if list.Type != "array" || list.Items.Ref != "#/components/schemas/library.Book" {
+		t.Fatalf("expected the books field as the response, got %+v", list)
+	}
+
+	watch := doc.Paths["/library.Library/WatchBooks"].Post
+	if resp := watch.Responses["200"].Content["application/json"].Schema; resp.Type != "array" {
+		t.Fatalf("expected a stream of books, got %+v", resp)
+	}
+	for _, name := range []string{"library.Book", "library.GetBookReq", "library.ListBooksResp", "google.rpc.Status"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("expected a %s component", name)
+		}
+	}
+}
+
+func TestOpenAPICustomRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		rules       string
+		unsupported string
+	}{
+		{"custom verb", `custom: { kind: "head" path: "/v1/books" }`, ""},
+		{"unsupported custom verb", `custom: { kind: "LOCK" path: "/v1/books" }`, `HTTP method "LOCK" is not supported by OpenAPI`},
+		{"duplicate", `get: "/v1/books" additional_bindings { get: "/v1/books" }`, "GET /v1/books is already bound to rules.Books.List"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"rules.proto": `syntax = "proto3";
+package rules;
+
+import "google/api/annotations.proto";
+
+message Req {}
+
+service Books {
+  rpc List (Req) returns (Req) {
+    option (google.api.http) = { ` + tt.rules + ` };
+  }
+  rpc Get (Req) returns (Req) {
+    option (google.api.http) = { get: "/v1/book" };
+  }
+}
+`}
+			for name, src := range googleAPIProtos {
+				files[name] = src
+			}
+			fd := parseTestProto(t, "rules.proto", files)
+			doc := NewOpenAPIDocument(fd.FindService("rules.Books").GetMethods(), OpenAPIOptions{})
+			if item := doc.Paths["/v1/book"]; item == nil || item.Get == nil {
+				t.Fatalf("expected the other methods to be described, got %+v", doc.Paths)
+			}
+			if tt.unsupported == "" {
+				if item := doc.Paths["/v1/books"]; item == nil || item.Head == nil || len(doc.UnsupportedRules) != 0 {
+					t.Fatalf("expected a HEAD operation, got %+v %+v", doc.Paths, doc.UnsupportedRules)
+				}
+				return
+			}
+			if len(doc.UnsupportedRules) != 1 || !strings.Contains(doc.UnsupportedRules[0].Reason, tt.unsupported) {
+				t.Fatalf("expected the rule to be reported as %q, got %+v", tt.unsupported, doc.UnsupportedRules)
+			}
+			b, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), `"x-unsupported-rules":[{"operationId":"rules.Books.List`) {
+				t.Fatalf("expected the rule in the x-unsupported-rules extension: %s", b)
+			}
+		})
+	}
+}
+
+func TestGetOpenAPI(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	doc, err := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r).GetOpenAPI(OpenAPIOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc.Info.Title != addr {
+		t.Fatalf("expected the host as the title, got %s", doc.Info.Title)
+	}
+	login := doc.Paths["/user.User/Login"]
+	if login == nil || login.Post == nil || login.Post.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/user.LoginReq" {
+		t.Fatalf("unexpected Login operation %+v", login)
+	}
+	if doc.Paths["/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"] != nil {
+		t.Fatal("expected reflection to be left out")
+	}
+}
+
+func TestRPCOpenAPIHandler(t *testing.T) {
+	fd := parseLibraryProto(t)
+	h := RPCOpenAPIHandler(fd.FindService("library.Library").GetMethods(), OpenAPIOptions{Title: "Library"})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Header())
+	}
+	var doc OpenAPIDocument
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Info.Title != "Library" || len(doc.Paths) != 5 {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/openapi.json", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected POST to be rejected, got %d", rec.Code)
+	}
+}
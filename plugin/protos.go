@@ -0,0 +1,199 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+)
+
+// fileSource returns the descriptor source built from the proto files and
+// protosets of g together with those uploaded for g.Host, or nil if there
+// are none.
+func (g *Grpc) fileSource() (grpcurl.DescriptorSource, error) {
+	sources, err := loadDescriptors(g.ImportPaths, g.ProtoFiles, g.Protosets)
+	if err != nil {
+		return nil, err
+	}
+	dir, protoFiles, protosets, err := uploadedProtos(g.Host)
+	if err != nil {
+		return nil, err
+	}
+	uploaded, err := loadDescriptors([]string{dir}, protoFiles, protosets)
+	if err != nil {
+		return nil, fmt.Errorf("could not load proto files uploaded for %s: %v", g.Host, err)
+	}
+	return append(sources, uploaded...).source(), nil
+}
+
+// loadDescriptors compiles protoFiles, resolved against importPaths, and
+// reads the FileDescriptorSets in protosets.
+func loadDescriptors(importPaths, protoFiles, protosets []string) (sources multiSource, err error) {
+	if len(protoFiles) > 0 {
+		names, err := protoparse.ResolveFilenames(importPaths, protoFiles...)
+		if err != nil {
+			return nil, err
+		}
+		p := protoparse.Parser{
+			ImportPaths:           importPaths,
+			InferImportPaths:      len(importPaths) == 0,
+			IncludeSourceCodeInfo: true,
+		}
+		fds, err := p.ParseFiles(names...)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse proto files: %v", err)
+		}
+		source, err := grpcurl.DescriptorSourceFromFileDescriptors(fds...)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	if len(protosets) > 0 {
+		source, err := grpcurl.DescriptorSourceFromProtoSets(protosets...)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// multiSource resolves symbols from the first source that knows them, so
+// proto files and protosets can describe the same dependencies without
+// conflicting.
+type multiSource []grpcurl.DescriptorSource
+
+// source returns ms as a single source, nil if it is empty.
+func (ms multiSource) source() grpcurl.DescriptorSource {
+	switch len(ms) {
+	case 0:
+		return nil
+	case 1:
+		return ms[0]
+	}
+	return ms
+}
+
+func (ms multiSource) ListServices() ([]string, error) {
+	var svcs []string
+	seen := map[string]bool{}
+	for _, s := range ms {
+		names, err := s.ListServices()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				svcs = append(svcs, name)
+			}
+		}
+	}
+	return svcs, nil
+}
+
+func (ms multiSource) FindSymbol(fullyQualifiedName string) (d desc.Descriptor, err error) {
+	for _, s := range ms {
+		if d, err = s.FindSymbol(fullyQualifiedName); err == nil {
+			return d, nil
+		}
+	}
+	return nil, err
+}
+
+func (ms multiSource) AllExtensionsForType(typeName string) ([]*desc.FieldDescriptor, error) {
+	var exts []*desc.FieldDescriptor
+	tags := make(map[int32]bool)
+	for _, s := range ms {
+		fileExts, err := s.AllExtensionsForType(typeName)
+		if err != nil {
+			return nil, err
+		}
+		for _, ext := range fileExts {
+			if !tags[ext.GetNumber()] {
+				tags[ext.GetNumber()] = true
+				exts = append(exts, ext)
+			}
+		}
+	}
+	return exts, nil
+}
+
+// protoDir is the folder under BasePath holding the files uploaded for host.
+func protoDir(host string) string {
+	return filepath.Join(BasePath, url.PathEscape(host))
+}
+
+// SaveProtoFile persists an uploaded .proto or .protoset file for host under
+// BasePath, from where it is loaded as a descriptor source by every
+// connection to host. name is the path of a .proto file relative to its
+// import root, e.g. "user/user.proto", so uploaded files can import each
+// other. Connections already open for host only see the file after a Reset.
+func SaveProtoFile(host, name string, content io.Reader) error {
+	name = filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid proto file name %q", name)
+	}
+	if !isProtoFile(name) && !isProtoset(name) {
+		return fmt.Errorf("%q is neither a .proto nor a .protoset file", name)
+	}
+	path := filepath.Join(protoDir(host), name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// uploadedProtos lists the files saved with SaveProtoFile for host: the
+// .proto files relative to dir, which is their import root, and the
+// protosets as full paths.
+func uploadedProtos(host string) (dir string, protoFiles, protosets []string, err error) {
+	dir = protoDir(host)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		switch {
+		case isProtoFile(path):
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			protoFiles = append(protoFiles, filepath.ToSlash(rel))
+		case isProtoset(path):
+			protosets = append(protosets, path)
+		}
+		return nil
+	})
+	return
+}
+
+func isProtoFile(name string) bool {
+	return filepath.Ext(name) == ".proto"
+}
+
+func isProtoset(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".protoset" || ext == ".pb"
+}
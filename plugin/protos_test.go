@@ -0,0 +1,153 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/test-instructor/grpc-plugin/demo"
+)
+
+const testProtoDir = "../demo/user"
+
+// writeTestProtoset compiles the demo user.proto into a protoset in dir.
+func writeTestProtoset(t *testing.T, dir string) string {
+	t.Helper()
+	fds, err := (&protoparse.Parser{ImportPaths: []string{testProtoDir}}).ParseFiles("user.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := proto.Marshal(desc.ToFileDescriptorSet(fds...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "user.protoset")
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProtoFilesWithoutReflection(t *testing.T) {
+	addr := serveTestSvc(t, demo.NewUserSvc())
+	protoset := writeTestProtoset(t, t.TempDir())
+
+	tests := []struct {
+		name string
+		g    *Grpc
+	}{
+		{"proto files", &Grpc{ImportPaths: []string{testProtoDir}, ProtoFiles: []string{"user.proto"}}},
+		{"protoset", &Grpc{Protosets: []string{protoset}}},
+		{"reflection disabled", &Grpc{Protosets: []string{protoset}, DisableReflection: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := tt.g
+			g.Host = addr
+			g.Method = "user.User.RegisterUser"
+			g.Body = strings.NewReader(fmt.Sprintf(`{"UserName":%q}`, uniqueName(t.Name())))
+			r := NewRegistry(0, 0)
+			defer r.CloseAll()
+			ig := NewInvokeGrpcWithRegistry(g, r)
+			if err := ig.GetResource(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			svcs, err := ig.GetSvs()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(svcs, []string{"user.User"}) {
+				t.Fatalf("unexpected services %v", svcs)
+			}
+			results, err := ig.InvokeFunction()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if results.Error != nil || len(results.Responses) != 1 {
+				t.Fatalf("expected one response, got %+v", results)
+			}
+		})
+	}
+}
+
+func TestReflectionDisabledWithoutProtos(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	if _, err := r.Get(context.Background(), &Grpc{Host: addr, DisableReflection: true}); err == nil {
+		t.Fatal("expected an error without any descriptor source")
+	}
+}
+
+func TestProtoFilesFallback(t *testing.T) {
+	addr := startTestSvc(t)
+	dir := t.TempDir()
+	extra := "syntax = \"proto3\";\npackage extra;\nmessage Extra { string name = 1; }\n"
+	if err := os.WriteFile(filepath.Join(dir, "extra.proto"), []byte(extra), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	c, err := r.Get(context.Background(), &Grpc{Host: addr, ImportPaths: []string{dir}, ProtoFiles: []string{"extra.proto"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.DescriptorSource().FindSymbol("user.User"); err != nil {
+		t.Fatalf("expected reflection to resolve user.User: %v", err)
+	}
+	if _, err := c.DescriptorSource().FindSymbol("extra.Extra"); err != nil {
+		t.Fatalf("expected the proto files to resolve extra.Extra: %v", err)
+	}
+
+	// without files, unknown symbols are an error rather than a panic
+	c, err = r.Get(context.Background(), &Grpc{Host: addr})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.DescriptorSource().FindSymbol("extra.Extra"); err == nil {
+		t.Fatal("expected an error for an unknown symbol")
+	}
+}
+
+func TestSaveProtoFile(t *testing.T) {
+	addr := serveTestSvc(t, demo.NewUserSvc())
+	t.Cleanup(func() { os.RemoveAll(protoDir(addr)) })
+
+	if err := SaveProtoFile(addr, "../user.proto", strings.NewReader("")); err == nil {
+		t.Fatal("expected an error for a name outside the upload folder")
+	}
+	if err := SaveProtoFile(addr, "user.txt", strings.NewReader("")); err == nil {
+		t.Fatal("expected an error for a file that is not a proto")
+	}
+
+	f, err := os.Open(filepath.Join(testProtoDir, "user.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := SaveProtoFile(addr, "user/user.proto", f); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r)
+	if err := ig.GetResource(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	methods, err := ig.GetMethod("user.User")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(methods) == 0 {
+		t.Fatal("expected the uploaded proto to describe user.User")
+	}
+}
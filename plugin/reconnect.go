@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// connLost reports whether a call failed with code because its connection
+// went away, rather than because of the call itself.
+func connLost(code codes.Code) bool {
+	return code == codes.Unavailable
+}
+
+// errConnLost is connLost for the errors of reflection lookups.
+func errConnLost(err error) bool {
+	return err != nil && connLost(status.Code(err))
+}
+
+// resend reports whether an RPC of md whose connection was lost may be sent
+// again. One that never got a stream sent nothing; otherwise the server may
+// have run it already, so only methods marked idempotent are sent again, if
+// retryIdempotent is set.
+func resend(md *desc.MethodDescriptor, started, retryIdempotent bool) bool {
+	if !started {
+		return true
+	}
+	return retryIdempotent && md.GetMethodOptions().GetIdempotencyLevel() != descriptor.MethodOptions_IDEMPOTENCY_UNKNOWN
+}
+
+// streamTracker is a channel recording whether an RPC got a stream on the
+// transport, after which its request may have reached the server.
+type streamTracker struct {
+	cc      grpc.ClientConnInterface
+	started int32
+}
+
+// Invoke does what grpc.ClientConn.Invoke does, except for unary
+// interceptors, which the connections of the plugin don't have, so that
+// unary RPCs are tracked too.
+func (t *streamTracker) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	cs, err := t.NewStream(ctx, &grpc.StreamDesc{}, method, opts...)
+	if err != nil {
+		return err
+	}
+	if err := cs.SendMsg(args); err != nil {
+		return err
+	}
+	return cs.RecvMsg(reply)
+}
+
+func (t *streamTracker) NewStream(ctx context.Context, sd *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	cs, err := t.cc.NewStream(ctx, sd, method, opts...)
+	if err == nil {
+		atomic.StoreInt32(&t.started, 1)
+	}
+	return cs, err
+}
+
+func (t *streamTracker) sent() bool {
+	return atomic.LoadInt32(&t.started) != 0
+}
+
+// withReconnect runs fn with the client for G. When fn reports that the
+// connection was lost, the client is dropped from the registry and fn runs
+// again on a new connection, at most Dial.MaxReconnects times and with an
+// exponential backoff. A new connection also fetches the descriptors again,
+// so a restarted server is seen with its current schema.
+func (i *InvokeGrpc) withReconnect(ctx context.Context, fn func(c *Client) (lost bool, err error)) error {
+	retries := i.G.Dial.maxReconnects()
+	backoff := i.G.Dial.reconnectBackoff()
+	for attempt := 0; ; attempt++ {
+		c, release, err := i.acquireClient(ctx)
+		if err != nil && attempt == 0 {
+			// the host was never reachable, there is nothing to reconnect
+			return err
+		}
+		if err == nil {
+			var lost bool
+			lost, err = fn(c)
+			release()
+			if !lost {
+				return err
+			}
+			i.getRegistry().invalidate(c)
+		}
+		if attempt >= retries {
+			return err
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+		if backoff *= 2; backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/test-instructor/grpc-plugin/demo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	greflection "google.golang.org/grpc/reflection"
+	reflectv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+// serveOn serves s on addr until the test ends.
+func serveOn(t *testing.T, addr string, s *grpc.Server) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return nil
+}
+
+func TestReconnectAfterRestart(t *testing.T) {
+	first := demo.NewSvc()
+	addr := serveTestSvc(t, first)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	g := &Grpc{Host: addr, Method: "grpc.health.v1.Health.Check", Dial: &DialConfig{ReconnectBackoff: 0.1}}
+	ig := NewInvokeGrpcWithRegistry(g, r)
+	if err := ig.GetResource(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before := registryClient(r, g)
+
+	// restart the server with a new service while the call is retrying
+	first.Stop()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(150 * time.Millisecond)
+		second := demo.NewSvc()
+		healthpb.RegisterHealthServer(second, health.NewServer())
+		if err := serveOn(t, addr, second); err != nil {
+			t.Errorf("failed to listen: %v", err)
+		}
+	}()
+	results, err := ig.InvokeFunction()
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results.Error != nil || len(results.Responses) != 1 {
+		t.Fatalf("expected the restarted server to answer, got %+v", results.Error)
+	}
+	if registryClient(r, g) == before {
+		t.Fatal("expected a new connection after the restart")
+	}
+}
+
+func TestReconnectDisabled(t *testing.T) {
+	s := demo.NewSvc()
+	addr := serveTestSvc(t, s)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	g := &Grpc{Host: addr, Method: "user.User.GetUserList", Dial: &DialConfig{MaxReconnects: -1}}
+	ig := NewInvokeGrpcWithRegistry(g, r)
+	if err := ig.GetResource(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before := registryClient(r, g)
+
+	s.Stop()
+	results, err := ig.InvokeFunction()
+	if err == nil && results.Error == nil {
+		t.Fatal("expected the call to fail while the server is down")
+	}
+	// the lost connection may be dropped, but no other one dialed
+	if c := registryClient(r, g); c != nil && c != before {
+		t.Fatal("expected no reconnect")
+	}
+}
+
+func TestNoResendOnceSent(t *testing.T) {
+	var calls int32
+	addr := startTestSvc(t, grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if info.FullMethod == "/user.User/GetUserList" && atomic.AddInt32(&calls, 1) == 1 {
+			return nil, status.Error(codes.Unavailable, "connection lost")
+		}
+		return handler(ctx, req)
+	}))
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	g := &Grpc{Host: addr, Dial: &DialConfig{ReconnectBackoff: 0.01, RetryIdempotent: true}}
+	ig := NewInvokeGrpcWithRegistry(g, r)
+
+	// the method isn't marked idempotent, the server may have run it
+	results, err := ig.Call(context.Background(), Request{Method: "user.User.GetUserList", Body: strings.NewReader("{}")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results.Error == nil || codes.Code(results.Error.Code) != codes.Unavailable {
+		t.Fatalf("expected the status of the call, got %+v after %d calls", results.Error, atomic.LoadInt32(&calls))
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected the RPC to be sent once, got %d", n)
+	}
+}
+
+func TestResend(t *testing.T) {
+	svc := parseOptionsProto(t).FindService("opts.Svc")
+	get, old := svc.FindMethodByName("Get"), svc.FindMethodByName("Old")
+	tests := []struct {
+		name                     string
+		started, retryIdempotent bool
+		get, old                 bool
+	}{
+		{"not sent", false, false, true, true},
+		{"sent", true, false, false, false},
+		{"sent with retry", true, true, true, false},
+	}
+	for _, tt := range tests {
+		if got := resend(get, tt.started, tt.retryIdempotent); got != tt.get {
+			t.Errorf("%s: expected %v for an idempotent method, got %v", tt.name, tt.get, got)
+		}
+		if got := resend(old, tt.started, tt.retryIdempotent); got != tt.old {
+			t.Errorf("%s: expected %v for another method, got %v", tt.name, tt.old, got)
+		}
+	}
+}
+
+// switchableServices hides the health service from reflection until shown.
+type switchableServices struct {
+	s    *grpc.Server
+	mu   sync.Mutex
+	show bool
+}
+
+func (p *switchableServices) GetServiceInfo() map[string]grpc.ServiceInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info := p.s.GetServiceInfo()
+	if !p.show {
+		delete(info, "grpc.health.v1.Health")
+	}
+	return info
+}
+
+func TestRefreshOnSchemaChange(t *testing.T) {
+	s := demo.NewUserSvc()
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	services := &switchableServices{s: s}
+	reflectv1alpha.RegisterServerReflectionServer(s, greflection.NewServer(greflection.ServerOptions{Services: services}))
+	addr := serveTestSvc(t, s)
+
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	g := &Grpc{Host: addr, Method: "grpc.health.v1.Health.Check"}
+	ig := NewInvokeGrpcWithRegistry(g, r)
+	if _, err := ig.InvokeFunction(); err == nil {
+		t.Fatal("expected an error for a method the server doesn't advertise")
+	}
+	c := registryClient(r, g)
+	idx, err := c.methodIndex(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ig.InvokeFunction(); err == nil {
+		t.Fatal("expected an error for a method the server doesn't advertise")
+	}
+	if next, _ := c.methodIndex(context.Background()); next != idx {
+		t.Fatal("expected the index to be kept while the schema is unchanged")
+	}
+
+	services.mu.Lock()
+	services.show = true
+	services.mu.Unlock()
+	// as if the last refresh were long ago
+	c.mu.Lock()
+	c.refreshed = time.Time{}
+	c.mu.Unlock()
+	results, err := ig.InvokeFunction()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results.Error != nil || len(results.Responses) != 1 {
+		t.Fatalf("unexpected result: %+v", results.Error)
+	}
+	if registryClient(r, g) != c {
+		t.Fatal("expected the schema to be refreshed on the same connection")
+	}
+}
+
+func TestRefreshRateLimit(t *testing.T) {
+	calls := &reflectionCalls{}
+	addr := startTestSvc(t, grpc.StreamInterceptor(calls.interceptor))
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r)
+	streams := func() int {
+		calls.mu.Lock()
+		defer calls.mu.Unlock()
+		return len(calls.services)
+	}
+
+	if _, err := ig.ResolveMethod(context.Background(), "Missing"); !errors.Is(err, ErrMethodNotFound) {
+		t.Fatalf("expected ErrMethodNotFound, got %v", err)
+	}
+	n := streams()
+	if _, err := ig.ResolveMethod(context.Background(), "Missing"); !errors.Is(err, ErrMethodNotFound) {
+		t.Fatalf("expected ErrMethodNotFound, got %v", err)
+	}
+	if streams() != n {
+		t.Fatalf("expected a single refresh, got %d more reflection streams", streams()-n)
+	}
+}
@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataProvider returns metadata that must be computed when it is sent,
+// such as a short-lived auth token.
+type MetadataProvider func(ctx context.Context) ([]RpcMetadata, error)
+
+// metadataHeaders formats md as the "name: value" headers used by grpcurl.
+func metadataHeaders(md []RpcMetadata) []string {
+	headers := make([]string, 0, len(md))
+	for _, m := range md {
+		headers = append(headers, fmt.Sprintf("%s: %s", m.Name, m.Value))
+	}
+	return headers
+}
+
+func isReflectionMethod(method string) bool {
+	return strings.HasPrefix(method, "/grpc.reflection.")
+}
+
+// reflectionInterceptor adds the metadata of provider to every reflection
+// stream when it is opened, leaving other RPCs alone.
+func reflectionInterceptor(provider MetadataProvider) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, sd *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if !isReflectionMethod(method) {
+			return streamer(ctx, sd, cc, method, opts...)
+		}
+		md, err := provider(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not get reflection metadata: %v", err)
+		}
+		for _, m := range md {
+			ctx = metadata.AppendToOutgoingContext(ctx, m.Name, m.Value)
+		}
+		return streamer(ctx, sd, cc, method, opts...)
+	}
+}
+
+// ReflectionUnauthorizedError is returned when the server rejects the
+// reflection requests as Unauthenticated or PermissionDenied, usually
+// because Grpc.ReflectMetadata lacks valid credentials.
+type ReflectionUnauthorizedError struct {
+	Host   string
+	Status *status.Status
+}
+
+func (e *ReflectionUnauthorizedError) Error() string {
+	return fmt.Sprintf("reflection on %s unauthorized: %s: %s", e.Host, e.Status.Code(), e.Status.Message())
+}
+
+func (e *ReflectionUnauthorizedError) Is(target error) bool {
+	return target == ErrReflection
+}
+
+// GRPCStatus returns the status the server rejected reflection with.
+func (e *ReflectionUnauthorizedError) GRPCStatus() *status.Status {
+	return e.Status
+}
+
+// reflectionSource reports the authorization failures of a reflection
+// source as *ReflectionUnauthorizedError.
+type reflectionSource struct {
+	grpcurl.DescriptorSource
+	host string
+}
+
+func (rs reflectionSource) check(err error) error {
+	if st, ok := status.FromError(err); ok && err != nil {
+		switch st.Code() {
+		case codes.Unauthenticated, codes.PermissionDenied:
+			return &ReflectionUnauthorizedError{Host: rs.host, Status: st}
+		}
+	}
+	return err
+}
+
+func (rs reflectionSource) ListServices() ([]string, error) {
+	svcs, err := rs.DescriptorSource.ListServices()
+	return svcs, rs.check(err)
+}
+
+func (rs reflectionSource) FindSymbol(fullyQualifiedName string) (desc.Descriptor, error) {
+	d, err := rs.DescriptorSource.FindSymbol(fullyQualifiedName)
+	return d, rs.check(err)
+}
+
+func (rs reflectionSource) AllExtensionsForType(typeName string) ([]*desc.FieldDescriptor, error) {
+	exts, err := rs.DescriptorSource.AllExtensionsForType(typeName)
+	return exts, rs.check(err)
+}
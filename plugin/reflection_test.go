@@ -0,0 +1,259 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/test-instructor/grpc-plugin/demo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	greflection "google.golang.org/grpc/reflection"
+	reflectv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+// registerV1Reflection serves grpc.reflection.v1 with the v1alpha
+// implementation of grpc-go, the messages of both versions are identical on
+// the wire. grpc-go's own v1 package can't be linked next to the copy used
+// by grpcreflect.
+func registerV1Reflection(s *grpc.Server) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "grpc.reflection.v1.ServerReflection",
+		HandlerType: (*reflectv1alpha.ServerReflectionServer)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName: "ServerReflectionInfo",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				return srv.(reflectv1alpha.ServerReflectionServer).ServerReflectionInfo(reflectionStream{stream})
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		}},
+		Metadata: "grpc/reflection/v1/reflection.proto",
+	}, greflection.NewServer(greflection.ServerOptions{Services: s}))
+}
+
+type reflectionStream struct {
+	grpc.ServerStream
+}
+
+func (s reflectionStream) Send(resp *reflectv1alpha.ServerReflectionResponse) error {
+	return s.SendMsg(resp)
+}
+
+func (s reflectionStream) Recv() (*reflectv1alpha.ServerReflectionRequest, error) {
+	req := new(reflectv1alpha.ServerReflectionRequest)
+	if err := s.RecvMsg(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// reflectionCalls records the reflection services a server was called on.
+type reflectionCalls struct {
+	mu       sync.Mutex
+	services []string
+}
+
+func (c *reflectionCalls) interceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	c.mu.Lock()
+	c.services = append(c.services, info.FullMethod[1:strings.LastIndex(info.FullMethod, "/")])
+	c.mu.Unlock()
+	return handler(srv, ss)
+}
+
+func (c *reflectionCalls) used(svc string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.services {
+		if s == svc {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReflectionVersions(t *testing.T) {
+	const (
+		v1      = "grpc.reflection.v1.ServerReflection"
+		v1alpha = "grpc.reflection.v1alpha.ServerReflection"
+	)
+	tests := []struct {
+		name     string
+		register func(s *grpc.Server)
+		want     string
+	}{
+		{"v1", registerV1Reflection, v1},
+		{"v1alpha", func(s *grpc.Server) { greflection.Register(s) }, v1alpha},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := &reflectionCalls{}
+			s := demo.NewUserSvc(grpc.StreamInterceptor(calls.interceptor))
+			tt.register(s)
+			healthpb.RegisterHealthServer(s, health.NewServer())
+			addr := serveTestSvc(t, s)
+
+			r := NewRegistry(0, 0)
+			defer r.CloseAll()
+			ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r)
+			if err := ig.GetResource(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			svcs, err := ig.GetSvs()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(svcs, []string{"user.User"}) {
+				t.Fatalf("expected infrastructure services to be hidden, got %v", svcs)
+			}
+			if _, err := ig.GetReq("user.User", "Login"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !calls.used(tt.want) {
+				t.Fatalf("expected %s to be used, got calls to %v", tt.want, calls.services)
+			}
+		})
+	}
+}
+
+func TestHiddenServicesCanBeInvoked(t *testing.T) {
+	s := demo.NewSvc()
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	addr := serveTestSvc(t, s)
+
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr, Method: "grpc.health.v1.Health.Check"}, r)
+	results, err := ig.InvokeFunctionContext(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results.Error != nil || len(results.Responses) != 1 {
+		t.Fatalf("expected a health check response, got %+v", results)
+	}
+}
+
+// authReflection rejects reflection streams without the token, and records
+// the authorization metadata of the other RPCs.
+type authReflection struct {
+	token string
+	mu    sync.Mutex
+	rpcMD []string
+}
+
+func (a *authReflection) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	md, _ := metadata.FromIncomingContext(ss.Context())
+	if strings.HasPrefix(info.FullMethod, "/grpc.reflection.") {
+		if auth := md.Get("authorization"); len(auth) != 1 || auth[0] != "Bearer "+a.token {
+			return status.Error(codes.Unauthenticated, "missing token")
+		}
+	}
+	return handler(srv, ss)
+}
+
+func (a *authReflection) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	a.mu.Lock()
+	a.rpcMD = append(a.rpcMD, md.Get("authorization")...)
+	a.mu.Unlock()
+	return handler(ctx, req)
+}
+
+func TestReflectMetadata(t *testing.T) {
+	auth := &authReflection{token: "secret"}
+	addr := startTestSvc(t, grpc.StreamInterceptor(auth.stream), grpc.UnaryInterceptor(auth.unary))
+	header := []RpcMetadata{{Name: "authorization", Value: "Bearer secret"}}
+
+	var provided int32
+	tests := []struct {
+		name    string
+		g       *Grpc
+		wantErr bool
+	}{
+		{"without metadata", &Grpc{}, true},
+		{"wrong token", &Grpc{ReflectMetadata: []RpcMetadata{{Name: "authorization", Value: "Bearer wrong"}}}, true},
+		{"static metadata", &Grpc{ReflectMetadata: header}, false},
+		{"provider", &Grpc{ReflectMetadataProvider: func(ctx context.Context) ([]RpcMetadata, error) {
+			atomic.AddInt32(&provided, 1)
+			return header, nil
+		}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := tt.g
+			g.Host = addr
+			g.Method = "user.User.RegisterUser"
+			g.Body = strings.NewReader(fmt.Sprintf(`{"UserName":%q}`, uniqueName(t.Name())))
+			r := NewRegistry(0, 0)
+			defer r.CloseAll()
+			results, err := NewInvokeGrpcWithRegistry(g, r).InvokeFunction()
+			if tt.wantErr {
+				var unauthorized *ReflectionUnauthorizedError
+				if !errors.As(err, &unauthorized) {
+					t.Fatalf("expected a ReflectionUnauthorizedError, got %v", err)
+				}
+				if status.Code(unauthorized) != codes.Unauthenticated {
+					t.Fatalf("unexpected status %v", unauthorized.Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if results.Error != nil {
+				t.Fatalf("unexpected RPC error: %+v", results.Error)
+			}
+		})
+	}
+	if atomic.LoadInt32(&provided) == 0 {
+		t.Fatal("expected the metadata provider to be called")
+	}
+	if len(auth.rpcMD) != 0 {
+		t.Fatalf("expected reflection metadata to stay off the RPC, got %v", auth.rpcMD)
+	}
+}
+
+func tokenProvider(token string) MetadataProvider {
+	return func(ctx context.Context) ([]RpcMetadata, error) {
+		return []RpcMetadata{{Name: "authorization", Value: "Bearer " + token}}, nil
+	}
+}
+
+func TestReflectMetadataProviderKey(t *testing.T) {
+	auth := &authReflection{token: "alice"}
+	addr := startTestSvc(t, grpc.StreamInterceptor(auth.stream))
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+
+	// both providers come from one function literal
+	alice := &Grpc{Host: addr, ReflectMetadataProvider: tokenProvider("alice")}
+	bob := &Grpc{Host: addr, ReflectMetadataProvider: tokenProvider("bob")}
+	if alice.connKey() == bob.connKey() {
+		t.Fatal("expected providers without a key not to share a connection")
+	}
+	if _, err := NewInvokeGrpcWithRegistry(alice, r).GetSvs(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var unauthorized *ReflectionUnauthorizedError
+	if _, err := NewInvokeGrpcWithRegistry(bob, r).GetSvs(); !errors.As(err, &unauthorized) {
+		t.Fatalf("expected bob's token to be sent, got %v", err)
+	}
+
+	alice.ReflectMetadataKey, bob.ReflectMetadataKey = "alice", "bob"
+	if alice.connKey() == bob.connKey() {
+		t.Fatal("expected different keys not to share a connection")
+	}
+	again := &Grpc{Host: addr, ReflectMetadataProvider: tokenProvider("alice"), ReflectMetadataKey: "alice"}
+	if alice.connKey() != again.connKey() {
+		t.Fatal("expected the same key to share a connection")
+	}
+}
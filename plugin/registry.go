@@ -0,0 +1,363 @@
+package plugin
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+)
+
+// DefaultRegistry is used by InvokeGrpc values created with NewInvokeGrpc.
+// It never evicts connections, matching the historical behaviour.
+var DefaultRegistry = NewRegistry(0, 0)
+
+// Client is a connection to a host together with the reflection client and
+// descriptor source used to resolve its services.
+type Client struct {
+	key  string
+	host string
+	cc   *grpc.ClientConn
+	ctx  context.Context
+	// newSource creates a new reflection client bound to ctx and descriptor
+	// source for cc, it is nil when reflection is disabled.
+	newSource func(ctx context.Context) (*grpcreflect.Client, grpcurl.DescriptorSource)
+
+	mu         sync.Mutex
+	refClient  *grpcreflect.Client
+	descSource grpcurl.DescriptorSource
+	index      *methodIndex
+	// refreshed is when the descriptors were last fetched again.
+	refreshed time.Time
+}
+
+// Host returns the address the client is connected to.
+func (c *Client) Host() string {
+	return c.host
+}
+
+// Conn returns the underlying connection.
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.cc
+}
+
+// DescriptorSource returns the source used to resolve services and messages.
+func (c *Client) DescriptorSource() grpcurl.DescriptorSource {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.descSource
+}
+
+// lookupSource returns the descriptor source for a lookup bound to ctx, and
+// a func to call once the lookup is done. Unless ctx is never done, the
+// source has a reflection stream of its own, which is cancelled with ctx
+// rather than left running on the shared stream of c.
+func (c *Client) lookupSource(ctx context.Context) (grpcurl.DescriptorSource, func()) {
+	if c.newSource == nil || ctx.Done() == nil {
+		return c.DescriptorSource(), func() {}
+	}
+	refClient, source := c.newSource(ctx)
+	return source, refClient.Reset
+}
+
+func (c *Client) close() {
+	c.mu.Lock()
+	refClient := c.refClient
+	c.mu.Unlock()
+	if refClient != nil {
+		refClient.Reset()
+	}
+	if c.cc != nil {
+		c.cc.Close()
+	}
+}
+
+// clientFuture is a registry entry for a client that may still be dialing.
+// Callers asking for the same key wait on ready, so a slow or unreachable
+// host only blocks its own callers.
+type clientFuture struct {
+	key      string
+	host     string
+	ready    chan struct{}
+	client   *Client
+	err      error
+	lastUsed time.Time
+	// refs counts the callers using the client. A future removed from the
+	// registry while in use is closed by its last release rather than right
+	// away, so that evictions don't cut off the RPCs in flight. Both are
+	// guarded by the registry lock.
+	refs    int
+	removed bool
+}
+
+func newClientFuture(key, host string) *clientFuture {
+	return &clientFuture{key: key, host: host, ready: make(chan struct{})}
+}
+
+func (f *clientFuture) resolve(c *Client, err error) {
+	f.client, f.err = c, err
+	close(f.ready)
+}
+
+func (f *clientFuture) wait(ctx context.Context) (*Client, error) {
+	select {
+	case <-f.ready:
+		return f.client, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// close closes the client once dialing has finished, without waiting for
+// a pending dial.
+func (f *clientFuture) close() {
+	closeResolved := func() {
+		if f.client != nil {
+			f.client.close()
+		}
+	}
+	select {
+	case <-f.ready:
+		closeResolved()
+	default:
+		go func() {
+			<-f.ready
+			closeResolved()
+		}()
+	}
+}
+
+// Registry owns the connections and descriptor sources shared by InvokeGrpc
+// values. Clients are keyed by host and connection settings (see
+// Grpc.connKey), so requests with different TLS or dial options don't share
+// a connection.
+type Registry struct {
+	// IdleTTL closes clients that haven't been used for this long. Zero
+	// keeps idle clients forever.
+	IdleTTL time.Duration
+	// MaxClients caps the number of open clients, closing the least
+	// recently used one when exceeded. Zero means no limit.
+	MaxClients int
+
+	mu      sync.Mutex
+	clients map[string]*list.Element
+	lru     *list.List // front is most recently used
+	now     func() time.Time
+}
+
+// NewRegistry creates a Registry with the given idle TTL and client cap, zero
+// disables the respective eviction.
+func NewRegistry(idleTTL time.Duration, maxClients int) *Registry {
+	return &Registry{
+		IdleTTL:    idleTTL,
+		MaxClients: maxClients,
+		clients:    make(map[string]*list.Element),
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+// Get returns the client for g, dialing the host if there is no open client
+// for it yet. The registry lock is not held while dialing: concurrent callers
+// for the same host share a single dial, and callers for other hosts are not
+// blocked by it. The dial itself is bounded by the dial timeout, not by ctx,
+// since other callers may be waiting for it; ctx only bounds how long this
+// caller waits.
+//
+// The client is closed as soon as it is evicted; InvokeGrpc keeps the
+// clients it calls through open until its calls finish.
+func (r *Registry) Get(ctx context.Context, g *Grpc) (*Client, error) {
+	c, release, err := r.acquire(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	release()
+	return c, nil
+}
+
+// acquire is like Get, but also returns a func releasing the client, which
+// is not closed before it is released even if it is evicted meanwhile.
+func (r *Registry) acquire(ctx context.Context, g *Grpc) (*Client, func(), error) {
+	key := g.connKey()
+	r.mu.Lock()
+	evicted := r.pruneLocked()
+	var f *clientFuture
+	e, ok := r.clients[key]
+	if ok {
+		f = r.touchLocked(e)
+	} else {
+		f = newClientFuture(key, g.Host)
+		var ev []*clientFuture
+		e, ev = r.storeLocked(f)
+		evicted = append(evicted, ev...)
+	}
+	f.refs++
+	r.mu.Unlock()
+	closeFutures(evicted)
+
+	if !ok {
+		// copy g, the caller may reuse it once it stops waiting
+		conf := *g
+		go func() {
+			c, err := newClient(&conf)
+			if err != nil {
+				// forget the failed dial so that the next caller tries again
+				r.mu.Lock()
+				if r.clients[key] == e {
+					r.removeLocked(e)
+				}
+				r.mu.Unlock()
+			}
+			f.resolve(c, err)
+		}()
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(func() { r.release(f) })
+	}
+	c, err := f.wait(ctx)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return c, release, nil
+}
+
+func (r *Registry) release(f *clientFuture) {
+	r.mu.Lock()
+	f.refs--
+	unused := f.removed && f.refs == 0
+	r.mu.Unlock()
+	if unused {
+		f.close()
+	}
+}
+
+// Reset dials the host of g again and replaces its cached client, closing the
+// previous one once unused.
+func (r *Registry) Reset(g *Grpc) (*Client, error) {
+	c, err := newClient(g)
+	if err != nil {
+		return nil, err
+	}
+	f := newClientFuture(c.key, c.host)
+	f.resolve(c, nil)
+	r.mu.Lock()
+	var evicted []*clientFuture
+	if e, ok := r.clients[c.key]; ok {
+		evicted = append(evicted, r.removeLocked(e))
+	}
+	_, ev := r.storeLocked(f)
+	evicted = append(evicted, ev...)
+	r.mu.Unlock()
+	closeFutures(evicted)
+	return c, nil
+}
+
+// invalidate forgets c, unless it was already replaced, so the next Get
+// dials its host again. c is closed once no caller uses it.
+func (r *Registry) invalidate(c *Client) {
+	r.mu.Lock()
+	var evicted []*clientFuture
+	if e, ok := r.clients[c.key]; ok {
+		f := e.Value.(*clientFuture)
+		select {
+		case <-f.ready:
+			if f.client == c {
+				evicted = append(evicted, r.removeLocked(e))
+			}
+		default:
+		}
+	}
+	r.mu.Unlock()
+	closeFutures(evicted)
+}
+
+// Close closes every client connected to host, whatever its settings. The
+// clients in use are closed when their calls finish.
+func (r *Registry) Close(host string) {
+	r.mu.Lock()
+	var evicted []*clientFuture
+	for _, e := range r.clients {
+		if e.Value.(*clientFuture).host == host {
+			evicted = append(evicted, r.removeLocked(e))
+		}
+	}
+	r.mu.Unlock()
+	closeFutures(evicted)
+}
+
+// CloseAll closes every client. The registry remains usable afterwards.
+func (r *Registry) CloseAll() {
+	r.mu.Lock()
+	var evicted []*clientFuture
+	for _, e := range r.clients {
+		evicted = append(evicted, r.removeLocked(e))
+	}
+	r.mu.Unlock()
+	closeFutures(evicted)
+}
+
+// Prune closes clients that have been idle for longer than IdleTTL. It is
+// also done on every Get, so calling it is only needed to release idle
+// connections when the registry is otherwise unused.
+func (r *Registry) Prune() {
+	r.mu.Lock()
+	evicted := r.pruneLocked()
+	r.mu.Unlock()
+	closeFutures(evicted)
+}
+
+func (r *Registry) touchLocked(e *list.Element) *clientFuture {
+	f := e.Value.(*clientFuture)
+	f.lastUsed = r.now()
+	r.lru.MoveToFront(e)
+	return f
+}
+
+func (r *Registry) storeLocked(f *clientFuture) (e *list.Element, evicted []*clientFuture) {
+	f.lastUsed = r.now()
+	e = r.lru.PushFront(f)
+	r.clients[f.key] = e
+	for r.MaxClients > 0 && r.lru.Len() > r.MaxClients {
+		evicted = append(evicted, r.removeLocked(r.lru.Back()))
+	}
+	return e, evicted
+}
+
+// removeLocked forgets the future of e. It returns the future if it is to be
+// closed now, or nil if it is in use and left to its last release.
+func (r *Registry) removeLocked(e *list.Element) *clientFuture {
+	f := r.lru.Remove(e).(*clientFuture)
+	delete(r.clients, f.key)
+	f.removed = true
+	if f.refs > 0 {
+		return nil
+	}
+	return f
+}
+
+func (r *Registry) pruneLocked() (evicted []*clientFuture) {
+	if r.IdleTTL <= 0 {
+		return nil
+	}
+	deadline := r.now().Add(-r.IdleTTL)
+	for e := r.lru.Back(); e != nil; e = r.lru.Back() {
+		if !e.Value.(*clientFuture).lastUsed.Before(deadline) {
+			break
+		}
+		evicted = append(evicted, r.removeLocked(e))
+	}
+	return evicted
+}
+
+func closeFutures(futures []*clientFuture) {
+	for _, f := range futures {
+		if f != nil {
+			f.close()
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestRegistryGet(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+
+	c1, err := r.Get(context.Background(), &Grpc{Host: addr})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c2, err := r.Get(context.Background(), &Grpc{Host: addr, Method: "user.User.Login"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c1 != c2 {
+		t.Fatal("expected the same client for the same host")
+	}
+
+	r.Close(addr)
+	if state := c1.Conn().GetState(); state != connectivity.Shutdown {
+		t.Fatalf("expected closed connection, got %v", state)
+	}
+	c3, err := r.Get(context.Background(), &Grpc{Host: addr})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c3 == c1 {
+		t.Fatal("expected a new client after Close")
+	}
+}
+
+func TestRegistryEvictInUse(t *testing.T) {
+	hosts := []string{startTestSvc(t), startTestSvc(t)}
+	r := NewRegistry(0, 1)
+	defer r.CloseAll()
+
+	c, release, err := r.acquire(context.Background(), &Grpc{Host: hosts[0]})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Get(context.Background(), &Grpc{Host: hosts[1]}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := r.clients[c.key]; ok {
+		t.Fatal("expected the client to be evicted")
+	}
+	if state := c.Conn().GetState(); state == connectivity.Shutdown {
+		t.Fatal("expected the client in use to stay open")
+	}
+	release()
+	release()
+	if state := c.Conn().GetState(); state != connectivity.Shutdown {
+		t.Fatalf("expected the released client to be closed, got %v", state)
+	}
+}
+
+func TestRegistryIdleTTL(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(time.Minute, 0)
+	defer r.CloseAll()
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	c, err := r.Get(context.Background(), &Grpc{Host: addr})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(30 * time.Second)
+	r.Prune()
+	if len(r.clients) != 1 {
+		t.Fatal("client evicted before its idle TTL")
+	}
+
+	now = now.Add(2 * time.Minute)
+	r.Prune()
+	if len(r.clients) != 0 {
+		t.Fatal("expected idle client to be evicted")
+	}
+	if state := c.Conn().GetState(); state != connectivity.Shutdown {
+		t.Fatalf("expected closed connection, got %v", state)
+	}
+}
+
+func TestRegistryMaxClients(t *testing.T) {
+	hosts := []string{startTestSvc(t), startTestSvc(t), startTestSvc(t)}
+	r := NewRegistry(0, 2)
+	defer r.CloseAll()
+
+	first, err := r.Get(context.Background(), &Grpc{Host: hosts[0]})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, h := range hosts[1:] {
+		if _, err := r.Get(context.Background(), &Grpc{Host: h}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(r.clients) != 2 {
+		t.Fatalf("expected 2 clients, got %d", len(r.clients))
+	}
+	if _, ok := r.clients[first.key]; ok {
+		t.Fatal("expected least recently used client to be evicted")
+	}
+
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: hosts[0]}, r)
+	if err := ig.GetResource(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if registryClient(r, ig.G) == first {
+		t.Fatal("expected evicted host to be dialed again")
+	}
+}
+
+// blackholeListener accepts connections but never speaks HTTP/2, so dials to
+// it block until they time out.
+func blackholeListener(t *testing.T) (addr string, accepted *int32) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	accepted = new(int32)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(accepted, 1)
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	return lis.Addr().String(), accepted
+}
+
+func TestRegistryIndependentHosts(t *testing.T) {
+	dead, accepted := blackholeListener(t)
+	live := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+
+	deadConf := &DialConfig{Timeout: 2}
+	var wg sync.WaitGroup
+	deadDone := make(chan struct{})
+	for n := 0; n < 5; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.Get(context.Background(), &Grpc{Host: dead, Dial: deadConf}); err == nil {
+				t.Error("expected dial to unreachable host to fail")
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(deadDone)
+	}()
+	// give the dead host's dial a head start
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := r.Get(context.Background(), &Grpc{Host: live}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("live host waited %v for the unreachable one", elapsed)
+	}
+	select {
+	case <-deadDone:
+		t.Fatal("unreachable host dial finished before its timeout")
+	default:
+	}
+
+	<-deadDone
+	if n := atomic.LoadInt32(accepted); n != 1 {
+		t.Fatalf("expected callers of the same host to share one dial, got %d connections", n)
+	}
+	if len(r.clients) != 1 {
+		t.Fatalf("expected failed dial to be forgotten, got %d clients", len(r.clients))
+	}
+}
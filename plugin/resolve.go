@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// maxSuggestions caps the names suggested for a method that isn't found.
+const maxSuggestions = 5
+
+// resolve finds the method name refers to, see Grpc.Method. If no method or
+// more than one matches, the error suggests the closest names.
+func (idx *methodIndex) resolve(name string) (*desc.MethodDescriptor, error) {
+	if md, ok := idx.methods[name]; ok {
+		return md, nil
+	}
+	full := normalizeMethodName(name)
+	if md, ok := idx.methods[full]; ok {
+		return md, nil
+	}
+
+	names := make([]string, 0, len(idx.methods))
+	for n := range idx.methods {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, equal := range []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		strings.EqualFold,
+	} {
+		var matches []string
+		for _, n := range names {
+			if equal(n, full) || (len(n) > len(full) && n[len(n)-len(full)-1] == '.' && equal(n[len(n)-len(full):], full)) {
+				matches = append(matches, n)
+			}
+		}
+		switch {
+		case len(matches) == 1:
+			return idx.methods[matches[0]], nil
+		case len(matches) > 1:
+			return nil, &Error{
+				Kind:        ErrMethodNotFound,
+				Subject:     name,
+				Err:         fmt.Errorf("%d methods match", len(matches)),
+				Suggestions: truncate(matches, maxSuggestions),
+			}
+		}
+	}
+	return nil, &Error{Kind: ErrMethodNotFound, Subject: name, Suggestions: suggest(names, full)}
+}
+
+// normalizeMethodName turns the slash form of a method name, e.g.
+// /user.User/Login, into the dotted one.
+func normalizeMethodName(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[:i] + "." + name[i+1:]
+	}
+	return name
+}
+
+// suggest ranks the names that are close to name by edit distance. Names
+// are compared by as many trailing components as name has, so that a
+// misspelled short name is close to the full name of its method.
+func suggest(names []string, name string) []string {
+	name = strings.ToLower(name)
+	parts := strings.Count(name, ".") + 1
+	maxDist := utf8.RuneCountInString(name) / 3
+	if maxDist < 1 {
+		maxDist = 1
+	}
+
+	type candidate struct {
+		name string
+		dist int
+	}
+	var candidates []candidate
+	for _, n := range names {
+		if d := editDistance(name, lastParts(strings.ToLower(n), parts)); d <= maxDist {
+			candidates = append(candidates, candidate{n, d})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].dist < candidates[j].dist
+	})
+	var suggestions []string
+	for _, c := range candidates {
+		suggestions = append(suggestions, c.name)
+	}
+	return truncate(suggestions, maxSuggestions)
+}
+
+// lastParts returns the last n dot-separated components of name.
+func lastParts(name string, n int) string {
+	i := len(name)
+	for ; n > 0 && i >= 0; n-- {
+		i = strings.LastIndex(name[:i], ".")
+	}
+	return name[i+1:]
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func truncate(s []string, n int) []string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// resolveMethod resolves name in the index of c, refreshing the index once
+// if nothing matches, as the server may have been updated with new methods.
+// Refreshes are at least minIndexRefresh apart.
+// lost reports whether the lookup failed because the connection was lost.
+func (c *Client) resolveMethod(ctx context.Context, name string) (md *desc.MethodDescriptor, idx *methodIndex, lost bool, err error) {
+	if idx, err = c.methodIndex(ctx); err != nil {
+		return nil, nil, errConnLost(err), lookupError(ctx, c.host, err)
+	}
+	if md, err = idx.resolve(name); err == nil {
+		return
+	}
+	if idx, err = c.refreshIndex(ctx); err != nil {
+		return nil, nil, errConnLost(err), lookupError(ctx, c.host, err)
+	}
+	md, err = idx.resolve(name)
+	return
+}
+
+// ResolveMethod returns the fully-qualified name of the method name refers
+// to on the host of G, see Grpc.Method. If it is not found, the
+// ErrMethodNotFound *Error lists the closest names in Suggestions.
+func (i *InvokeGrpc) ResolveMethod(ctx context.Context, name string) (string, error) {
+	md, _, err := i.resolve(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return md.GetFullyQualifiedName(), nil
+}
+
+// resolve resolves name on the host of G, reconnecting if needed. It also
+// returns the index name was found in.
+func (i *InvokeGrpc) resolve(ctx context.Context, name string) (md *desc.MethodDescriptor, idx *methodIndex, err error) {
+	err = i.withReconnect(ctx, func(c *Client) (lost bool, err error) {
+		md, idx, lost, err = c.resolveMethod(ctx, name)
+		return
+	})
+	return
+}
@@ -0,0 +1,104 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/test-instructor/grpc-plugin/demo"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestResolveMethod(t *testing.T) {
+	s := demo.NewSvc()
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	addr := serveTestSvc(t, s)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r)
+	ctx := context.Background()
+
+	for _, name := range []string{
+		"user.User.Login",
+		"user.User/Login",
+		"/user.User/Login",
+		"User.Login",
+		"Login",
+		"user.user.login",
+		"user.login",
+		"LOGIN",
+	} {
+		got, err := ig.ResolveMethod(ctx, name)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		} else if got != "user.User.Login" {
+			t.Errorf("%s: resolved to %s", name, got)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		suggestions []string
+	}{
+		{"Lgin", []string{"user.User.Login"}},
+		{"User.GetUserLst", []string{"user.User.GetUserList"}},
+		{"user.Usr/Chat", []string{"user.User.Chat"}},
+		{"Nothing.Like.It", nil},
+	}
+	for _, tt := range tests {
+		_, err := ig.ResolveMethod(ctx, tt.name)
+		var e *Error
+		if !errors.As(err, &e) || e.Kind != ErrMethodNotFound {
+			t.Fatalf("%s: expected ErrMethodNotFound, got %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(e.Suggestions, tt.suggestions) {
+			t.Errorf("%s: got suggestions %v, want %v", tt.name, e.Suggestions, tt.suggestions)
+		}
+	}
+
+	ig.G.Method = "Chek"
+	_, err := ig.InvokeFunction()
+	if want := "method not found: Chek (did you mean grpc.health.v1.Health.Check?)"; err == nil || err.Error() != want {
+		t.Fatalf("got %v, want %s", err, want)
+	}
+	ig.G.Method = "health.check"
+	results, err := ig.InvokeFunction()
+	if err != nil || results.Error != nil {
+		t.Fatalf("unexpected error: %v %+v", err, results)
+	}
+}
+
+func TestResolveAmbiguous(t *testing.T) {
+	idx := &methodIndex{methods: map[string]*desc.MethodDescriptor{}}
+	for _, name := range []string{"a.v1.Svc.Get", "b.v1.Svc.Get", "a.v1.Svc.List"} {
+		idx.methods[name] = nil
+	}
+	_, err := idx.resolve("Svc.Get")
+	var e *Error
+	if !errors.As(err, &e) || !reflect.DeepEqual(e.Suggestions, []string{"a.v1.Svc.Get", "b.v1.Svc.Get"}) {
+		t.Fatalf("expected both methods to be suggested, got %v", err)
+	}
+	if _, err := idx.resolve("b.v1.Svc.Get"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"login", "login", 0},
+		{"lgin", "login", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	} {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
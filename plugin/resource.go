@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,7 +17,6 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
 // BasePath define path where proto file will persisted
@@ -36,11 +36,22 @@ type Resource struct {
 func (r *Resource) openDescriptor() error {
 	ctx := context.Background()
 	refCtx := metadata.NewOutgoingContext(ctx, r.md)
-	r.refClient = grpcreflect.NewClient(refCtx, reflectpb.NewServerReflectionClient(r.clientConn))
-
-	// if no protos available use server reflection
+	r.refClient = grpcreflect.NewClientAuto(refCtx, r.clientConn)
 
 	r.descSource = grpcurl.DescriptorSourceFromServer(ctx, r.refClient)
+
+	// protos uploaded for the server fill in what reflection doesn't know
+	dir, protoFiles, protosets, err := uploadedProtos(r.clientConn.Target())
+	if err != nil {
+		return err
+	}
+	fileSource, err := loadDescriptors([]string{dir}, protoFiles, protosets)
+	if err != nil {
+		return err
+	}
+	if len(fileSource) > 0 {
+		r.descSource = compositeSource{reflection: r.descSource, file: fileSource.source()}
+	}
 	return nil
 
 }
@@ -141,31 +152,43 @@ func (r *Resource) Describe(symbol string) (string, string, error) {
 			return "", "", err
 		}
 
-		txt, err := grpcurl.GetDescriptorText(dsc, r.descSource)
+		txt, tmpl, err := describeDescriptor(dsc, r.descSource)
 		if err != nil {
 			return "", "", err
 		}
 		result = txt
-
-		if dsc, ok := dsc.(*desc.MessageDescriptor); ok {
-			// for messages, also show a template in JSON, to make it easier to
-			// create a request to invoke an RPC
-			tmpl := grpcurl.MakeTemplate(dsc)
-			_, formatter, err := grpcurl.RequestParserAndFormatterFor(grpcurl.Format("json"), r.descSource, true, false, nil)
-			if err != nil {
-				return "", "", err
-			}
-			str, err := formatter(tmpl)
-			if err != nil {
-				return "", "", err
-			}
-			template = str
+		if tmpl != "" {
+			template = tmpl
 		}
 	}
 
 	return result, template, nil
 }
 
+// describeDescriptor returns the proto source of dsc and, for messages, a
+// JSON template of it.
+func describeDescriptor(dsc desc.Descriptor, source grpcurl.DescriptorSource) (txt, template string, err error) {
+	txt, err = grpcurl.GetDescriptorText(dsc, source)
+	if err != nil {
+		return "", "", err
+	}
+
+	if dsc, ok := dsc.(*desc.MessageDescriptor); ok {
+		// for messages, also show a template in JSON, to make it easier to
+		// create a request to invoke an RPC
+		tmpl := grpcurl.MakeTemplate(dsc)
+		_, formatter, err := grpcurl.RequestParserAndFormatterFor(grpcurl.Format("json"), source, true, false, strings.NewReader(""))
+		if err != nil {
+			return "", "", err
+		}
+		template, err = formatter(tmpl)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return txt, template, nil
+}
+
 // Invoke - invoking gRPC function
 func (r *Resource) Invoke(ctx context.Context, metadata []string, symbol string, in io.Reader) (string, time.Duration, error) {
 	err := r.openDescriptor()
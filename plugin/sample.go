@@ -0,0 +1,328 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+)
+
+const (
+	defaultSampleDepth   = 3
+	defaultSampleEntries = 2
+	defaultSampleLength  = 8
+)
+
+// sampleChars are the characters of sample strings, those of the demo's
+// RandAllString.
+const sampleChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
+
+// sampleEpoch is the earliest sample timestamp, fixed so that a seed always
+// generates the same sample.
+var sampleEpoch = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SampleOptions tune the generated sample messages.
+type SampleOptions struct {
+	// Seed makes samples reproducible, the same seed generates the same
+	// sample. Zero picks a random seed.
+	Seed int64
+	// MaxDepth is how deep messages nest at most, so that recursive
+	// messages end. Message fields below are left out. Defaults to 3.
+	MaxDepth int
+	// Entries is the number of entries of repeated and map fields, defaults
+	// to 2.
+	Entries int
+	// StringLength is the length of sample strings, defaults to 8.
+	StringLength int
+	// UseProtoNames names fields after the proto files, instead of their
+	// lowerCamelCase JSON names.
+	UseProtoNames bool
+}
+
+// SampleMessage generates a message of type md with plausible random
+// values in the protojson form: every field is set, repeated and map
+// fields have entries, one field of each oneof is picked, and the
+// well-known types get valid values, e.g. RFC 3339 timestamps.
+func SampleMessage(md *desc.MessageDescriptor, opts SampleOptions) (json.RawMessage, error) {
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	g := &sampler{opts: opts, rand: rand.New(rand.NewSource(seed))}
+	if g.opts.MaxDepth <= 0 {
+		g.opts.MaxDepth = defaultSampleDepth
+	}
+	if g.opts.Entries <= 0 {
+		g.opts.Entries = defaultSampleEntries
+	}
+	if g.opts.StringLength <= 0 {
+		g.opts.StringLength = defaultSampleLength
+	}
+	return json.Marshal(g.message(md, 0))
+}
+
+// GetSample generates a sample request of method, by any name Grpc.Method
+// accepts, see SampleMessage. Client-streaming methods get an array of
+// Entries requests, as InvokeGrpc takes them.
+func (i *InvokeGrpc) GetSample(method string, opts SampleOptions) (json.RawMessage, error) {
+	return i.GetSampleContext(context.Background(), method, opts)
+}
+
+// GetSampleContext is like GetSample, but the reflection lookup is bound to
+// ctx.
+func (i *InvokeGrpc) GetSampleContext(ctx context.Context, method string, opts SampleOptions) (json.RawMessage, error) {
+	md, _, err := i.resolve(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	if !md.IsClientStreaming() {
+		return SampleMessage(md.GetInputType(), opts)
+	}
+	// the messages of a stream differ, so each gets its own seed
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	n := opts.Entries
+	if n <= 0 {
+		n = defaultSampleEntries
+	}
+	msgs := make([]json.RawMessage, n)
+	for k := range msgs {
+		opts.Seed = seed + int64(k)
+		if msgs[k], err = SampleMessage(md.GetInputType(), opts); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(msgs)
+}
+
+type sampler struct {
+	opts SampleOptions
+	rand *rand.Rand
+}
+
+// sampleField is a field of a sampleObject.
+type sampleField struct {
+	name  string
+	value interface{}
+}
+
+// sampleObject is a JSON object that keeps its fields in the order of the
+// message.
+type sampleObject []sampleField
+
+func (o sampleObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for n, f := range o {
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// skip is returned for values that are left out.
+type skip struct{}
+
+func (g *sampler) message(md *desc.MessageDescriptor, depth int) interface{} {
+	if wk, ok := g.wellKnown(md); ok {
+		return wk
+	}
+	obj := sampleObject{}
+	picked := map[*desc.OneOfDescriptor]*desc.FieldDescriptor{}
+	for _, ood := range md.GetOneOfs() {
+		if !ood.IsSynthetic() {
+			choices := ood.GetChoices()
+			picked[ood] = choices[g.rand.Intn(len(choices))]
+		}
+	}
+	for _, fd := range md.GetFields() {
+		if ood := fd.GetOneOf(); ood != nil && !ood.IsSynthetic() && picked[ood] != fd {
+			continue
+		}
+		v := g.field(fd, depth)
+		if _, ok := v.(skip); ok {
+			continue
+		}
+		name := fd.GetJSONName()
+		if g.opts.UseProtoNames {
+			name = fd.GetName()
+		}
+		obj = append(obj, sampleField{name, v})
+	}
+	return obj
+}
+
+// omit reports whether a field of type md is left out at depth: messages
+// below MaxDepth, and Any, which needs a type the server knows. The
+// well-known types with special JSON forms are values rather than nested
+// messages.
+func (g *sampler) omit(md *desc.MessageDescriptor, depth int) bool {
+	if md == nil {
+		return false
+	}
+	name := md.GetFullyQualifiedName()
+	if _, ok := wellKnownSchemas[name]; ok {
+		return name == "google.protobuf.Any"
+	}
+	return depth+1 >= g.opts.MaxDepth
+}
+
+func (g *sampler) field(fd *desc.FieldDescriptor, depth int) interface{} {
+	switch {
+	case fd.IsMap():
+		if g.omit(fd.GetMapValueType().GetMessageType(), depth) {
+			return skip{}
+		}
+		obj := sampleObject{}
+		seen := map[string]bool{}
+		for n := 0; n < g.opts.Entries; n++ {
+			key := fmt.Sprint(g.scalar(fd.GetMapKeyType()))
+			if seen[key] {
+				// e.g. a bool key has two values only
+				continue
+			}
+			seen[key] = true
+			obj = append(obj, sampleField{key, g.singular(fd.GetMapValueType(), depth)})
+		}
+		return obj
+	case g.omit(fd.GetMessageType(), depth):
+		return skip{}
+	case fd.IsRepeated():
+		values := make([]interface{}, g.opts.Entries)
+		for n := range values {
+			values[n] = g.singular(fd, depth)
+		}
+		return values
+	default:
+		return g.singular(fd, depth)
+	}
+}
+
+func (g *sampler) singular(fd *desc.FieldDescriptor, depth int) interface{} {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return g.message(fd.GetMessageType(), depth+1)
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		return g.enum(fd.GetEnumType())
+	default:
+		return g.scalar(fd)
+	}
+}
+
+// enum picks a value of ed, other than the zero value if there is one, as
+// that usually means unspecified.
+func (g *sampler) enum(ed *desc.EnumDescriptor) interface{} {
+	if ed.GetFullyQualifiedName() == "google.protobuf.NullValue" {
+		return nil
+	}
+	var candidates []string
+	for _, v := range ed.GetValues() {
+		if v.GetNumber() != 0 {
+			candidates = append(candidates, v.GetName())
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = append(candidates, ed.GetValues()[0].GetName())
+	}
+	return candidates[g.rand.Intn(len(candidates))]
+}
+
+func (g *sampler) scalar(fd *desc.FieldDescriptor) interface{} {
+	return g.scalarOf(fd.GetType())
+}
+
+func (g *sampler) scalarOf(t descriptor.FieldDescriptorProto_Type) interface{} {
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return g.string()
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		b := make([]byte, g.opts.StringLength)
+		g.rand.Read(b)
+		return base64.StdEncoding.EncodeToString(b)
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return g.rand.Intn(2) == 1
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32, descriptor.FieldDescriptorProto_TYPE_SFIXED32,
+		descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return g.rand.Intn(1000) + 1
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64,
+		descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		// 64-bit integers are strings in JSON
+		return strconv.Itoa(g.rand.Intn(1000000) + 1)
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT, descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return float64(g.rand.Intn(100000)) / 100
+	default:
+		return g.string()
+	}
+}
+
+func (g *sampler) string() string {
+	var sb strings.Builder
+	for n := 0; n < g.opts.StringLength; n++ {
+		sb.WriteByte(sampleChars[g.rand.Intn(len(sampleChars))])
+	}
+	return sb.String()
+}
+
+// wrapperTypes maps the wrapper well-known types to the type they wrap.
+var wrapperTypes = map[string]descriptor.FieldDescriptorProto_Type{
+	"google.protobuf.DoubleValue": descriptor.FieldDescriptorProto_TYPE_DOUBLE,
+	"google.protobuf.FloatValue":  descriptor.FieldDescriptorProto_TYPE_FLOAT,
+	"google.protobuf.Int64Value":  descriptor.FieldDescriptorProto_TYPE_INT64,
+	"google.protobuf.UInt64Value": descriptor.FieldDescriptorProto_TYPE_UINT64,
+	"google.protobuf.Int32Value":  descriptor.FieldDescriptorProto_TYPE_INT32,
+	"google.protobuf.UInt32Value": descriptor.FieldDescriptorProto_TYPE_UINT32,
+	"google.protobuf.BoolValue":   descriptor.FieldDescriptorProto_TYPE_BOOL,
+	"google.protobuf.StringValue": descriptor.FieldDescriptorProto_TYPE_STRING,
+	"google.protobuf.BytesValue":  descriptor.FieldDescriptorProto_TYPE_BYTES,
+}
+
+// wellKnown generates the values of the well-known types with special
+// JSON forms.
+func (g *sampler) wellKnown(md *desc.MessageDescriptor) (interface{}, bool) {
+	name := md.GetFullyQualifiedName()
+	if t, ok := wrapperTypes[name]; ok {
+		return g.scalarOf(t), true
+	}
+	switch name {
+	case "google.protobuf.Timestamp":
+		ts := sampleEpoch.Add(time.Duration(g.rand.Int63n(int64(365 * 24 * time.Hour))))
+		return ts.Truncate(time.Millisecond).Format(time.RFC3339Nano), true
+	case "google.protobuf.Duration":
+		return fmt.Sprintf("%d.%03ds", g.rand.Intn(3600), g.rand.Intn(1000)), true
+	case "google.protobuf.FieldMask":
+		return strings.ToLower(g.string()), true
+	case "google.protobuf.Struct":
+		return sampleObject{{g.string(), g.string()}}, true
+	case "google.protobuf.Value":
+		return g.string(), true
+	case "google.protobuf.ListValue":
+		return []interface{}{g.string()}, true
+	case "google.protobuf.Any":
+		// an Any needs a type the server knows
+		return sampleObject{}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+func TestSampleMessage(t *testing.T) {
+	md := parseSchemaProto(t).FindMessage("schema.Node")
+
+	first, err := SampleMessage(md, SampleOptions{Seed: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, _ := SampleMessage(md, SampleOptions{Seed: 1})
+	if string(first) != string(again) {
+		t.Fatalf("expected the same seed to generate the same sample:\n%s\n%s", first, again)
+	}
+	other, _ := SampleMessage(md, SampleOptions{Seed: 2})
+	if string(first) == string(other) {
+		t.Fatal("expected another seed to generate another sample")
+	}
+
+	for seed := int64(1); seed <= 20; seed++ {
+		sample, err := SampleMessage(md, SampleOptions{Seed: seed})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := dynamic.NewMessage(md).UnmarshalJSON(sample); err != nil {
+			t.Fatalf("seed %d: invalid sample %s: %v", seed, sample, err)
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(sample, &fields); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := fields["text"]; ok == (fields["number"] != nil) {
+			t.Fatalf("expected exactly one oneof field to be set: %s", sample)
+		}
+		if _, ok := fields["detail"]; ok {
+			t.Fatalf("expected Any to be left out: %s", sample)
+		}
+		for _, name := range []string{"id", "weight", "data", "color", "children", "labels", "flag", "created", "ttl", "attrs", "count"} {
+			if _, ok := fields[name]; !ok {
+				t.Fatalf("expected %s to be set: %s", name, sample)
+			}
+		}
+		if string(fields["color"]) != `"RED"` {
+			t.Fatalf("expected the zero enum value to be avoided, got %s", fields["color"])
+		}
+	}
+}
+
+func TestSampleMaxDepth(t *testing.T) {
+	md := parseSchemaProto(t).FindMessage("schema.Node")
+	for depth := 1; depth <= 4; depth++ {
+		sample, err := SampleMessage(md, SampleOptions{Seed: 1, MaxDepth: depth, Entries: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// each level but the last holds a single child
+		if got := strings.Count(string(sample), `"children"`); got != depth-1 {
+			t.Errorf("depth %d: got %d levels of children: %s", depth, got, sample)
+		}
+	}
+}
+
+func TestGetSample(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r)
+
+	body, err := ig.GetSample("RegisterUser", SampleOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	results, err := ig.Call(context.Background(), Request{Method: "RegisterUser", Body: strings.NewReader(string(body))})
+	if err != nil || results.Error != nil {
+		t.Fatalf("expected the sample to be accepted: %v %+v", err, results)
+	}
+
+	body, err = ig.GetSample("RegisterUsers", SampleOptions{Entries: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var msgs []json.RawMessage
+	if err := json.Unmarshal(body, &msgs); err != nil || len(msgs) != 3 {
+		t.Fatalf("expected 3 requests for a client stream, got %s", body)
+	}
+	if string(msgs[0]) == string(msgs[1]) {
+		t.Fatalf("expected the requests of a stream to differ: %s", body)
+	}
+}
@@ -0,0 +1,418 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GrpcStep is a gRPC call with the extract and validate blocks of an hrp
+// step. hrp has no gRPC step type, testcases pass it to the grpc_step
+// function instead, see GrpcRunStep. In JSON:
+//
+//	{
+//	  "name": "login",
+//	  "grpc": {
+//	    "host": "127.0.0.1:40061",
+//	    "method": "user.User.Login",
+//	    "metadata": {"id": 1},
+//	    "body": {"UserName": "user", "P": "secret"},
+//	    "timeout": 3
+//	  },
+//	  "extract": {"token": "response.Token"},
+//	  "validate": [
+//	    {"check": "error", "assert": "equals", "expect": null},
+//	    {"eq": ["headers.username", "user"]}
+//	  ]
+//	}
+//
+// or the same in YAML. Extract and validate paths address the result of the
+// step, see GrpcStepResult.
+type GrpcStep struct {
+	Name     string            `json:"name"`
+	Grpc     *GrpcStepRequest  `json:"grpc"`
+	Extract  map[string]string `json:"extract,omitempty"`
+	Validate []StepValidator   `json:"validate,omitempty"`
+}
+
+// GrpcStepRequest is the RPC of a GrpcStep.
+type GrpcStepRequest struct {
+	Host   string `json:"host"`
+	Method string `json:"method"`
+	// Metadata values of any type are sent as strings.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// Body is the request, as an object or as a string holding its JSON.
+	// Messages are the requests of a client-streaming or bidi method, in
+	// place of Body.
+	Body     json.RawMessage   `json:"body,omitempty"`
+	Messages []json.RawMessage `json:"messages,omitempty"`
+	// Timeout is in seconds.
+	Timeout float32    `json:"timeout,omitempty"`
+	TLS     *TLSConfig `json:"tls,omitempty"`
+}
+
+// StepValidator checks a value of the result of a step. Besides
+// {"check": path, "assert": name, "expect": value} it may be written in the
+// short hrp form {name: [path, value]}. The assertions are equals (eq),
+// not_equal (ne), contains, length_equals (len_eq), greater_than (gt),
+// less_than (lt) and regex_match.
+type StepValidator struct {
+	Check   string      `json:"check"`
+	Assert  string      `json:"assert"`
+	Expect  interface{} `json:"expect"`
+	Message string      `json:"msg,omitempty"`
+}
+
+func (v *StepValidator) UnmarshalJSON(data []byte) error {
+	type validator StepValidator
+	var full validator
+	if err := json.Unmarshal(data, &full); err != nil {
+		return err
+	}
+	if full.Check != "" {
+		*v = StepValidator(full)
+		return nil
+	}
+	var short map[string][]interface{}
+	if err := json.Unmarshal(data, &short); err != nil || len(short) != 1 {
+		return fmt.Errorf("invalid validator %s", data)
+	}
+	for assert, args := range short {
+		if len(args) != 2 {
+			return fmt.Errorf("invalid validator %s, expected [path, value]", data)
+		}
+		check, ok := args[0].(string)
+		if !ok {
+			return fmt.Errorf("invalid validator %s, expected [path, value]", data)
+		}
+		*v = StepValidator{Check: check, Assert: assert, Expect: args[1]}
+	}
+	return nil
+}
+
+// ParseGrpcStep parses a step from its JSON or YAML text, as a string or
+// []byte, or from its decoded form, such as the maps a YAML decoder returns.
+func ParseGrpcStep(step interface{}) (*GrpcStep, error) {
+	var data []byte
+	switch step := step.(type) {
+	case []byte:
+		data = step
+	case string:
+		data = []byte(step)
+	}
+	// JSON is decoded as is, so that bodies keep their numbers exactly
+	if data != nil && !json.Valid(data) {
+		var decoded interface{}
+		if err := yaml.Unmarshal(data, &decoded); err != nil {
+			return nil, fmt.Errorf("invalid step: %v", err)
+		}
+		step, data = decoded, nil
+	}
+	if data == nil {
+		var err error
+		if data, err = json.Marshal(stringKeys(step)); err != nil {
+			return nil, fmt.Errorf("invalid step: %v", err)
+		}
+	}
+	var s GrpcStep
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid step: %v", err)
+	}
+	if s.Grpc == nil || s.Grpc.Host == "" || s.Grpc.Method == "" {
+		return nil, fmt.Errorf("invalid step %q: grpc.host and grpc.method are required", s.Name)
+	}
+	if len(s.Grpc.Body) > 0 && len(s.Grpc.Messages) > 0 {
+		return nil, fmt.Errorf("invalid step %q: grpc.body and grpc.messages are exclusive", s.Name)
+	}
+	return &s, nil
+}
+
+// stringKeys converts the map[interface{}]interface{} of YAML decoders to
+// map[string]interface{}, which encoding/json supports.
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = stringKeys(val)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[k] = stringKeys(val)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for n, val := range v {
+			l[n] = stringKeys(val)
+		}
+		return l
+	}
+	return v
+}
+
+// GrpcStepResult is the outcome of a step. Data holds the result of the RPC
+// as extract and validate paths address it:
+//
+//	headers     the response headers, by name
+//	trailers    the response trailers, by name
+//	responses   the response messages
+//	response    the first response message
+//	error       null, or the code, name and message of the status
+//	elapsed_ms  the duration of the RPC in milliseconds
+//
+// Paths are dot separated, with list indexes as numbers or in brackets,
+// e.g. responses.0.UserName or responses[0].UserName.
+type GrpcStepResult struct {
+	Name      string                 `json:"name"`
+	Data      map[string]interface{} `json:"data"`
+	Variables map[string]interface{} `json:"variables"`
+	// Failures describes the validators that failed.
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Run calls the RPC of the step, then extracts its variables and checks its
+// validators. The RPC failing with a status is not an error of Run, it is
+// up to the validators. An error is returned if the RPC could not be made,
+// or if a variable could not be extracted or a validator failed, in which
+// case the result is returned as well.
+func (s *GrpcStep) Run(ctx context.Context) (*GrpcStepResult, error) {
+	return s.run(ctx, NewInvokeGrpc)
+}
+
+func (s *GrpcStep) run(ctx context.Context, newInvoke func(*Grpc) *InvokeGrpc) (*GrpcStepResult, error) {
+	start := time.Now()
+	results, err := newInvoke(&Grpc{Host: s.Grpc.Host, TLS: s.Grpc.TLS}).Call(ctx, s.Grpc.request())
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ResultMap(results)
+	if err != nil {
+		return nil, err
+	}
+	data["error"] = nil
+	if results.Error != nil {
+		data["error"] = map[string]interface{}{
+			"code":    results.Error.Code,
+			"name":    results.Error.Name,
+			"message": results.Error.Message,
+		}
+	}
+	data["elapsed_ms"] = float64(elapsed) / float64(time.Millisecond)
+	// the same types as expected values decoded from JSON or YAML
+	var normalized map[string]interface{}
+	if err := roundTrip(data, &normalized); err != nil {
+		return nil, err
+	}
+
+	result := &GrpcStepResult{Name: s.Name, Data: normalized, Variables: map[string]interface{}{}}
+	names := make([]string, 0, len(s.Extract))
+	for name := range s.Extract {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v, err := lookupPath(normalized, s.Extract[name])
+		if err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("extract %s: %v", name, err))
+			continue
+		}
+		result.Variables[name] = v
+	}
+	for _, v := range s.Validate {
+		if err := v.validate(normalized); err != nil {
+			result.Failures = append(result.Failures, err.Error())
+		}
+	}
+	if len(result.Failures) > 0 {
+		return result, fmt.Errorf("step %q failed: %s", s.Name, strings.Join(result.Failures, "; "))
+	}
+	return result, nil
+}
+
+func (r *GrpcStepRequest) request() Request {
+	req := Request{
+		Method:   r.Method,
+		Metadata: functionMetadata(r.Metadata),
+		Timeout:  r.Timeout,
+	}
+	if len(r.Messages) > 0 {
+		msgs := r.Messages
+		req.Messages = func() (json.RawMessage, error) {
+			if len(msgs) == 0 {
+				return nil, io.EOF
+			}
+			msg := msgs[0]
+			msgs = msgs[1:]
+			return msg, nil
+		}
+		return req
+	}
+	body := r.Body
+	var text string
+	if err := json.Unmarshal(body, &text); err == nil {
+		// the JSON of the body given as a string
+		body = json.RawMessage(text)
+	}
+	if len(body) > 0 {
+		req.Body = bytes.NewReader(body)
+	}
+	return req
+}
+
+func roundTrip(in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+var pathIndex = regexp.MustCompile(`\[(\d+)\]`)
+
+// lookupPath returns the value at path in v, see GrpcStepResult.
+func lookupPath(v interface{}, path string) (interface{}, error) {
+	path = pathIndex.ReplaceAllString(path, ".$1")
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = node[key]; !ok {
+				return nil, fmt.Errorf("%s: no field %s", path, key)
+			}
+		case []interface{}:
+			n, err := strconv.Atoi(key)
+			if err != nil || n < 0 || n >= len(node) {
+				return nil, fmt.Errorf("%s: no index %s of %d values", path, key, len(node))
+			}
+			v = node[n]
+		default:
+			return nil, fmt.Errorf("%s: %s of a %T", path, key, v)
+		}
+	}
+	return v, nil
+}
+
+func (v *StepValidator) validate(data map[string]interface{}) error {
+	got, err := lookupPath(data, v.Check)
+	if err != nil {
+		return v.failure("%v", err)
+	}
+	var want interface{}
+	if err := roundTrip(v.Expect, &want); err != nil {
+		return v.failure("invalid expected value: %v", err)
+	}
+
+	var ok bool
+	switch v.Assert {
+	case "equals", "equal", "eq":
+		ok = reflect.DeepEqual(got, want)
+	case "not_equal", "ne":
+		ok = !reflect.DeepEqual(got, want)
+	case "contains":
+		switch got := got.(type) {
+		case string:
+			s, isString := want.(string)
+			ok = isString && strings.Contains(got, s)
+		case []interface{}:
+			for _, elem := range got {
+				ok = ok || reflect.DeepEqual(elem, want)
+			}
+		case map[string]interface{}:
+			if key, isString := want.(string); isString {
+				_, ok = got[key]
+			}
+		}
+	case "length_equals", "len_eq":
+		n, isNumber := want.(float64)
+		ok = isNumber && float64(length(got)) == n
+	case "greater_than", "gt", "less_than", "lt":
+		g, gotNumber := got.(float64)
+		w, wantNumber := want.(float64)
+		if v.Assert == "greater_than" || v.Assert == "gt" {
+			ok = gotNumber && wantNumber && g > w
+		} else {
+			ok = gotNumber && wantNumber && g < w
+		}
+	case "regex_match":
+		s, isString := got.(string)
+		pattern, isPattern := want.(string)
+		if !isString || !isPattern {
+			break
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return v.failure("invalid pattern: %v", err)
+		}
+		ok = re.MatchString(s)
+	default:
+		return v.failure("unknown assertion %q", v.Assert)
+	}
+	if !ok {
+		return v.failure("got %s", jsonText(got))
+	}
+	return nil
+}
+
+func (v *StepValidator) failure(format string, args ...interface{}) error {
+	msg := fmt.Sprintf("%s %s %s: ", v.Check, v.Assert, jsonText(v.Expect)) + fmt.Sprintf(format, args...)
+	if v.Message != "" {
+		msg = v.Message + ": " + msg
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// length is the number of characters of a string, or the length of a list
+// or object, or -1 for other values.
+func length(v interface{}) int {
+	switch v := v.(type) {
+	case string:
+		return utf8.RuneCountInString(v)
+	case []interface{}:
+		return len(v)
+	case map[string]interface{}:
+		return len(v)
+	}
+	return -1
+}
+
+func jsonText(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// GrpcRunStep runs a step, given as ParseGrpcStep takes it, and returns its
+// result as a map, see GrpcStepResult.
+func GrpcRunStep(step interface{}) (map[string]interface{}, error) {
+	s, err := ParseGrpcStep(step)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.Run(context.Background())
+	if result == nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if rerr := roundTrip(result, &m); rerr != nil {
+		return nil, rerr
+	}
+	return m, err
+}
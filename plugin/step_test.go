@@ -0,0 +1,138 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGrpcStep(t *testing.T) {
+	s, err := ParseGrpcStep(`{"name":"login","grpc":{"host":"h","method":"m","body":{"a":1}},
+		"validate":[{"eq":["error",null]},{"check":"response.a","assert":"gt","expect":0,"msg":"positive"}]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []StepValidator{
+		{Check: "error", Assert: "eq"},
+		{Check: "response.a", Assert: "gt", Expect: 0.0, Message: "positive"},
+	}
+	if !reflect.DeepEqual(s.Validate, want) {
+		t.Fatalf("unexpected validators %+v", s.Validate)
+	}
+
+	// as a YAML decoder returns it
+	s, err = ParseGrpcStep(map[interface{}]interface{}{
+		"name": "yaml",
+		"grpc": map[interface{}]interface{}{"host": "h", "method": "m", "metadata": map[interface{}]interface{}{"id": 1}},
+	})
+	if err != nil || s.Grpc.Host != "h" || s.Grpc.Metadata["id"] != 1.0 {
+		t.Fatalf("unexpected step %+v %v", s, err)
+	}
+
+	s, err = ParseGrpcStep(`
+name: yaml text
+grpc:
+  host: h
+  method: m
+  body: {UserName: user}
+validate:
+  - eq: [error, null]
+`)
+	if err != nil || s.Name != "yaml text" || string(s.Grpc.Body) != `{"UserName":"user"}` || len(s.Validate) != 1 {
+		t.Fatalf("unexpected step %+v %v", s, err)
+	}
+
+	for _, step := range []string{
+		`{"grpc":{"host":"h"}}`,
+		"grpc: [h",
+		`{"grpc":{"host":"h","method":"m","body":{},"messages":[{}]}}`,
+		`{"grpc":{"host":"h","method":"m"},"validate":[{"eq":["error"]}]}`,
+	} {
+		if _, err := ParseGrpcStep(step); err == nil {
+			t.Errorf("expected %s to be rejected", step)
+		}
+	}
+}
+
+func TestGrpcStepRun(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	user, s1, s2 := uniqueName("step"), uniqueName("s1"), uniqueName("s2")
+	vars := strings.NewReplacer("$host", addr, "$user", user, "$s1", s1, "$s2", s2)
+	run := func(step string) (*GrpcStepResult, error) {
+		t.Helper()
+		s, err := ParseGrpcStep(vars.Replace(step))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.run(context.Background(), func(g *Grpc) *InvokeGrpc {
+			return NewInvokeGrpcWithRegistry(g, r)
+		})
+	}
+
+	if _, err := run(`{"name":"register","grpc":{"host":"$host","method":"RegisterUser","body":"{\"UserName\":\"$user\",\"Pwd\":\"secret\"}"},
+		"validate":[{"eq":["response.UserName","$user"]}]}`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := run(`{"name":"login","grpc":{"host":"$host","method":"user.User.Login","metadata":{"id":1},"body":{"UserName":"$user","P":"secret"},"timeout":3},
+		"extract":{"token":"response.Token","user":"responses[0].UserName"},
+		"validate":[
+			{"check":"error","assert":"equals","expect":null},
+			{"eq":["headers.username","$user"]},
+			{"len_eq":["response.Token",32]},
+			{"regex_match":["response.Token","^[a-zA-Z0-9]+$"]},
+			{"gt":["elapsed_ms",0]}
+		]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Variables["user"] != user || len(result.Variables["token"].(string)) != 32 {
+		t.Fatalf("unexpected variables %v", result.Variables)
+	}
+
+	_, err = run(`{"name":"wrong password","grpc":{"host":"$host","method":"Login","body":{"UserName":"$user","P":"wrong"}},
+		"validate":[{"eq":["error.code",2]},{"eq":["error.name","Unknown"]},{"contains":["error.message","密码错误"]},{"eq":["responses",[]]}]}`)
+	if err != nil {
+		t.Fatalf("expected a status to be up to the validators: %v", err)
+	}
+
+	result, err = run(`{"name":"stream","grpc":{"host":"$host","method":"RegisterUsers","messages":[{"UserName":"$s1"},{"UserName":"$s2"}]},
+		"extract":{"missing":"response.Missing"},
+		"validate":[{"len_eq":["response.Users",2]},{"check":"response.Users","assert":"contains","expect":"$s1","msg":"users"}]}`)
+	if err == nil || result == nil || len(result.Failures) != 2 {
+		t.Fatalf("expected the extraction and the last validator to fail, got %v %+v", err, result)
+	}
+	if !strings.HasPrefix(result.Failures[1], fmt.Sprintf("users: response.Users contains %q: got ", s1)) {
+		t.Fatalf("unexpected failure %q", result.Failures[1])
+	}
+}
+
+func TestStepValidatorLength(t *testing.T) {
+	data := map[string]interface{}{"message": "成功", "list": []interface{}{1.0, 2.0}}
+	for _, v := range []StepValidator{
+		{Check: "message", Assert: "length_equals", Expect: 2.0},
+		{Check: "list", Assert: "len_eq", Expect: 2.0},
+	} {
+		if err := v.validate(data); err != nil {
+			t.Errorf("%s: unexpected failure: %v", v.Check, err)
+		}
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	v := map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": "x"}}}
+	for _, path := range []string{"a.0.b", "a[0].b"} {
+		if got, err := lookupPath(v, path); err != nil || got != "x" {
+			t.Errorf("%s: got %v %v", path, got, err)
+		}
+	}
+	for _, path := range []string{"a.1.b", "a.0.c", "a.0.b.c", "b"} {
+		if _, err := lookupPath(v, path); err == nil {
+			t.Errorf("%s: expected an error", path)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// StreamOptions controls how InvokeStream consumes the responses of a
+// server-streaming RPC. The stream runs until the server ends it or any of
+// the stop conditions is met; stopping the stream that way is not reported
+// as an error.
+type StreamOptions struct {
+	// OnMessage is called with each response as soon as it arrives. When it
+	// is set, responses are not collected in RpcResult.Responses, so
+	// long-lived streams don't accumulate memory. To consume responses from
+	// a channel, send them to it from OnMessage.
+	OnMessage func(RpcResponseElement)
+	// MaxMessages stops the stream after this many responses.
+	MaxMessages int
+	// Duration stops the stream after this many seconds.
+	Duration float32
+	// Until stops the stream after the first response it returns true for.
+	// It receives the JSON form of the response, see JSONFieldEquals.
+	Until func(json.RawMessage) bool
+}
+
+// InvokeStream is InvokeStreamContext with a background context.
+func (i *InvokeGrpc) InvokeStream(opts StreamOptions) (*RpcResult, error) {
+	return i.InvokeStreamContext(context.Background(), opts)
+}
+
+// InvokeStreamContext invokes Grpc.Method like InvokeFunctionContext, but
+// delivers responses incrementally and stops the stream according to opts.
+func (i *InvokeGrpc) InvokeStreamContext(ctx context.Context, opts StreamOptions) (*RpcResult, error) {
+	return i.invokeStream(ctx, i.G.request(), opts)
+}
+
+// CallStream is like InvokeStreamContext, but takes the method and request
+// from req instead of G.
+func (i *InvokeGrpc) CallStream(ctx context.Context, req Request, opts StreamOptions) (*RpcResult, error) {
+	return i.invokeStream(ctx, req, opts)
+}
+
+func (i *InvokeGrpc) invokeStream(ctx context.Context, req Request, opts StreamOptions) (*RpcResult, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	stopped := false
+	stop := func() {
+		mu.Lock()
+		stopped = true
+		mu.Unlock()
+		cancel()
+	}
+	if opts.Duration > 0 {
+		timer := time.AfterFunc(seconds(opts.Duration), stop)
+		defer timer.Stop()
+	}
+
+	count := 0
+	onResponse := func(resp RpcResponseElement) bool {
+		mu.Lock()
+		done := stopped
+		mu.Unlock()
+		if done {
+			// drop messages that were already in flight when we stopped
+			return false
+		}
+		if opts.OnMessage != nil {
+			opts.OnMessage(resp)
+		}
+		count++
+		if (opts.MaxMessages > 0 && count >= opts.MaxMessages) || (opts.Until != nil && opts.Until(resp.Data)) {
+			stop()
+		}
+		return true
+	}
+
+	results, err := i.invoke(streamCtx, req, invokeHooks{
+		onResponse:       onResponse,
+		discardResponses: opts.OnMessage != nil,
+	})
+	if results == nil {
+		return results, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// the cancellation caused by a stop condition is not an error, unless
+	// the caller's context was cancelled as well
+	if stopped && ctx.Err() == nil && results.Error != nil && results.Error.Code == uint32(codes.Canceled) {
+		results.Error = nil
+	}
+	return results, err
+}
+
+// JSONFieldEquals returns a predicate for StreamOptions.Until that matches
+// responses whose field at path equals value. The path is a dot separated
+// list of field names as they appear in the JSON form of the response, e.g.
+// "user.id". Values are compared after a JSON round trip, so 64-bit integers,
+// which protojson renders as strings, must be given as strings.
+func JSONFieldEquals(path string, value interface{}) func(json.RawMessage) bool {
+	var want interface{}
+	if b, err := json.Marshal(value); err == nil {
+		_ = json.Unmarshal(b, &want)
+	}
+	keys := strings.Split(path, ".")
+	return func(msg json.RawMessage) bool {
+		var got interface{}
+		if err := json.Unmarshal(msg, &got); err != nil {
+			return false
+		}
+		for _, k := range keys {
+			obj, ok := got.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if got, ok = obj[k]; !ok {
+				return false
+			}
+		}
+		return reflect.DeepEqual(got, want)
+	}
+}
@@ -0,0 +1,191 @@
+package plugin
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func watchUsers(addr string, count int) *InvokeGrpc {
+	body, _ := json.Marshal(map[string]interface{}{"Count": count, "IntervalMs": 10})
+	return NewInvokeGrpc(&Grpc{
+		Host:   addr,
+		Method: "user.User.WatchUsers",
+		Body:   strings.NewReader(string(body)),
+	})
+}
+
+func TestInvokeStream(t *testing.T) {
+	addr := startTestSvc(t)
+
+	t.Run("server ends stream", func(t *testing.T) {
+		res, err := watchUsers(addr, 3).InvokeStream(StreamOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Error != nil || len(res.Responses) != 3 {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+	})
+
+	t.Run("max messages", func(t *testing.T) {
+		var got []RpcResponseElement
+		res, err := watchUsers(addr, 0).InvokeStream(StreamOptions{
+			MaxMessages: 4,
+			OnMessage:   func(resp RpcResponseElement) { got = append(got, resp) },
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Error != nil {
+			t.Fatalf("unexpected RPC error: %+v", res.Error)
+		}
+		if len(got) != 4 || len(res.Responses) != 0 {
+			t.Fatalf("expected 4 delivered and none collected, got %d and %d", len(got), len(res.Responses))
+		}
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		start := time.Now()
+		res, err := watchUsers(addr, 0).InvokeStream(StreamOptions{Duration: 0.2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("stream ran for %v", elapsed)
+		}
+		if res.Error != nil || len(res.Responses) == 0 {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+	})
+
+	t.Run("until", func(t *testing.T) {
+		res, err := watchUsers(addr, 0).InvokeStream(StreamOptions{Until: JSONFieldEquals("Seq", 5)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Error != nil || len(res.Responses) != 5 {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+		if !JSONFieldEquals("Seq", 5)(res.Responses[4].Data) {
+			t.Fatalf("unexpected last message: %s", res.Responses[4].Data)
+		}
+	})
+}
+
+func TestJSONFieldEquals(t *testing.T) {
+	msg := json.RawMessage(`{"user": {"id": "12", "tags": ["a"]}, "ok": true}`)
+	tests := []struct {
+		path  string
+		value interface{}
+		want  bool
+	}{
+		{"user.id", "12", true},
+		{"user.id", 12, false},
+		{"user.tags", []string{"a"}, true},
+		{"ok", true, true},
+		{"user.missing", nil, false},
+		{"ok.nested", true, false},
+	}
+	for _, tt := range tests {
+		if got := JSONFieldEquals(tt.path, tt.value)(msg); got != tt.want {
+			t.Errorf("JSONFieldEquals(%q, %v) = %v, want %v", tt.path, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestClientStreaming(t *testing.T) {
+	addr := startTestSvc(t)
+	prefix := "cs-" + strconv.Itoa(rand.Intn(1000000))
+
+	tests := []struct {
+		name string
+		g    *Grpc
+	}{
+		{"json array", &Grpc{Body: strings.NewReader(`[{"UserName": "` + prefix + `-a1"}, {"UserName": "` + prefix + `-a2"}]`)}},
+		{"ndjson", &Grpc{Body: strings.NewReader("{\"UserName\": \"" + prefix + "-n1\"}\n{\"UserName\": \"" + prefix + "-n2\"}\n")}},
+		{"message source", &Grpc{SendInterval: 0.05, Messages: func() MessageSource {
+			n := 0
+			return func() (json.RawMessage, error) {
+				if n == 2 {
+					return nil, io.EOF
+				}
+				n++
+				return json.RawMessage(`{"UserName": "` + prefix + `-m` + strconv.Itoa(n) + `"}`), nil
+			}
+		}()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.g.Host = addr
+			tt.g.Method = "user.User.RegisterUsers"
+			start := time.Now()
+			res, err := NewInvokeGrpc(tt.g).InvokeFunction()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Error != nil || len(res.Responses) != 1 {
+				t.Fatalf("unexpected result: %+v", res)
+			}
+			if res.Requests.Sent != 2 || res.Requests.Total != 2 {
+				t.Fatalf("expected 2 requests, got %+v", res.Requests)
+			}
+			var resp struct{ Users []struct{ UserName string } }
+			if err := json.Unmarshal(res.Responses[0].Data, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Users) != 2 {
+				t.Fatalf("expected 2 registered users, got %s", res.Responses[0].Data)
+			}
+			if tt.g.SendInterval > 0 && time.Since(start) < 50*time.Millisecond {
+				t.Fatal("requests were not paced")
+			}
+		})
+	}
+}
+
+func TestBidiConversation(t *testing.T) {
+	addr := startTestSvc(t)
+	var seen []string
+	g := &Grpc{
+		Host:   addr,
+		Method: "user.User.Chat",
+		Conversation: func(prev *RpcResponseElement) (json.RawMessage, error) {
+			if prev == nil {
+				return json.RawMessage(`{"Text": "hello"}`), nil
+			}
+			var resp struct {
+				Seq  int
+				Text string
+			}
+			if err := json.Unmarshal(prev.Data, &resp); err != nil {
+				return nil, err
+			}
+			seen = append(seen, resp.Text)
+			if resp.Seq == 3 {
+				return nil, io.EOF
+			}
+			return json.Marshal(map[string]string{"Text": resp.Text + "+" + strconv.Itoa(resp.Seq)})
+		},
+	}
+	res, err := NewInvokeGrpc(g).InvokeFunction()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Error != nil || len(res.Responses) != 3 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	want := []string{"hello", "hello+1", "hello+1+2"}
+	if strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected conversation %v, got %v", want, seen)
+	}
+
+	g.Method = "user.User.RegisterUsers"
+	if _, err := NewInvokeGrpc(g).InvokeFunction(); err == nil {
+		t.Fatal("expected error for a conversation with a client-streaming method")
+	}
+}
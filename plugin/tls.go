@@ -0,0 +1,58 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fullstorydev/grpcurl"
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSConfig describes how the connection to Grpc.Host is secured. A nil
+// *TLSConfig means the connection is plaintext.
+type TLSConfig struct {
+	// CACert is the path to a PEM encoded CA bundle used to verify the
+	// server certificate. The system roots are used when it is empty.
+	CACert string `json:"ca_cert"`
+	// Cert and Key are the paths to the PEM encoded client certificate and
+	// private key used for mutual TLS. Both or neither must be set.
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+	// ServerName overrides the name used to verify the server certificate,
+	// which otherwise defaults to the host part of Grpc.Host.
+	ServerName string `json:"server_name"`
+	// InsecureSkipVerify disables verification of the server certificate.
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+}
+
+func (t *TLSConfig) credentials() (credentials.TransportCredentials, error) {
+	if t == nil {
+		return nil, nil
+	}
+	if (t.Cert == "") != (t.Key == "") {
+		return nil, errors.New("both cert and key must be set to use mutual TLS")
+	}
+	tlsConf, err := grpcurl.ClientTLSConfig(t.InsecureSkipVerify, t.CACert, t.Cert, t.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TLS config: %v", err)
+	}
+	if t.ServerName != "" {
+		tlsConf.ServerName = t.ServerName
+	}
+	return credentials.NewTLS(tlsConf), nil
+}
+
+// tlsHandshakeError reports a failed TLS handshake with the target, as
+// opposed to a failure to establish the underlying TCP connection.
+type tlsHandshakeError struct {
+	addr string
+	err  error
+}
+
+func (e tlsHandshakeError) Error() string {
+	return fmt.Sprintf("TLS handshake with %s failed: %v", e.addr, e.err)
+}
+
+func (e tlsHandshakeError) Unwrap() error {
+	return e.err
+}
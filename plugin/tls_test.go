@@ -0,0 +1,181 @@
+package plugin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert creates a certificate signed by parent (self-signed when parent
+// is nil) and writes it to PEM files in dir.
+func newTestCert(t *testing.T, dir, name string, parent *testCert, dnsNames ...string) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     dnsNames,
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".pem"),
+		keyFile:  filepath.Join(dir, name+"-key.pem"),
+	}
+	writePEM(t, tc.certFile, "CERTIFICATE", der)
+	writePEM(t, tc.keyFile, "EC PRIVATE KEY", keyDer)
+	return tc
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (tc *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{tc.cert.Raw}, PrivateKey: tc.key}
+}
+
+type tlsFixture struct {
+	ca, server, client *testCert
+}
+
+func newTLSFixture(t *testing.T) *tlsFixture {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil)
+	return &tlsFixture{
+		ca:     ca,
+		server: newTestCert(t, dir, "server", ca, "localhost", "demo.local"),
+		client: newTestCert(t, dir, "client", ca),
+	}
+}
+
+func (f *tlsFixture) serve(t *testing.T, mutual bool) string {
+	conf := &tls.Config{Certificates: []tls.Certificate{f.server.tlsCertificate()}}
+	if mutual {
+		pool := x509.NewCertPool()
+		pool.AddCert(f.ca.cert)
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return startTestSvc(t, grpc.Creds(credentials.NewTLS(conf)))
+}
+
+func listServices(t *testing.T, g *Grpc) ([]string, error) {
+	t.Helper()
+	ig := NewInvokeGrpc(g)
+	if err := ig.GetResource(); err != nil {
+		return nil, err
+	}
+	return ig.GetSvs()
+}
+
+func TestTLSConnect(t *testing.T) {
+	f := newTLSFixture(t)
+
+	tests := []struct {
+		name      string
+		localhost bool
+		tls       *TLSConfig
+	}{
+		{"ca bundle", true, &TLSConfig{CACert: f.ca.certFile}},
+		{"server name override", false, &TLSConfig{CACert: f.ca.certFile, ServerName: "demo.local"}},
+		{"insecure skip verify", false, &TLSConfig{InsecureSkipVerify: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := f.serve(t, false)
+			if tt.localhost {
+				_, port, _ := net.SplitHostPort(host)
+				host = net.JoinHostPort("localhost", port)
+			}
+			svc, err := listServices(t, &Grpc{Host: host, TLS: tt.tls})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(svc) != 1 || svc[0] != "user.User" {
+				t.Fatalf("unexpected services: %v", svc)
+			}
+		})
+	}
+}
+
+func TestTLSHandshakeError(t *testing.T) {
+	f := newTLSFixture(t)
+	addr := f.serve(t, false)
+
+	// the server certificate is not valid for 127.0.0.1
+	_, err := listServices(t, &Grpc{Host: addr, TLS: &TLSConfig{CACert: f.ca.certFile}})
+	var herr tlsHandshakeError
+	if !errors.As(err, &herr) {
+		t.Fatalf("expected TLS handshake error, got %v", err)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	f := newTLSFixture(t)
+	addr := f.serve(t, true)
+
+	_, err := listServices(t, &Grpc{Host: addr, TLS: &TLSConfig{Cert: f.client.certFile}})
+	if err == nil {
+		t.Fatal("expected error when key is missing")
+	}
+
+	tlsConf := &TLSConfig{CACert: f.ca.certFile, ServerName: "localhost", Cert: f.client.certFile, Key: f.client.keyFile}
+	if _, err := listServices(t, &Grpc{Host: addr, TLS: tlsConf}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package plugin
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fullstorydev/grpcui/standalone"
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/grpc"
+
+	"github.com/test-instructor/grpc-plugin/plugin/internal"
+)
+
+// UIOptions tune the web UI served by ServeUI.
+type UIOptions struct {
+	// Services and Methods limit the UI to these services and methods, by
+	// fully-qualified names. All services but the infrastructure ones, such
+	// as reflection, are shown if both are empty.
+	Services []string
+	Methods  []string
+	// ExtraJS and ExtraCSS are files added to the page, Assets are files and
+	// folders served along with it, all under their base names.
+	ExtraJS  []string
+	ExtraCSS []string
+	Assets   []string
+	// DefaultMetadata prefills the metadata of the form. Metadata, as
+	// "name: value" pairs, is sent with every RPC, and so are the HTTP
+	// headers named in PreserveHeaders; both override the metadata of the
+	// form.
+	DefaultMetadata []string
+	Metadata        []string
+	PreserveHeaders []string
+	// Verbosity logs every request when greater than zero, and dumps
+	// requests when greater than one, with their bodies when greater than
+	// two.
+	Verbosity int
+	// BasePath serves the UI under a path other than /.
+	BasePath string
+}
+
+// ServeUI serves the grpcui web UI for the services of host on addr, e.g.
+// ":8080", until the server fails. The UI shares the cached connection of
+// DefaultRegistry, see UIHandler.
+func ServeUI(host, addr string, opts UIOptions) error {
+	h, err := NewInvokeGrpc(&Grpc{Host: host}).UIHandler(opts)
+	if err != nil {
+		return err
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+	path := opts.BasePath
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	internal.LogInfof("gRPC Web UI for %s available at http://%s%s", host, lis.Addr(), path)
+	return http.Serve(lis, h)
+}
+
+// UIHandler returns an HTTP handler serving the grpcui web UI for the
+// services of G.Host. The methods are listed with the connection and
+// descriptor source of the registry, and RPCs use whatever connection the
+// registry holds for G when they are invoked, so the UI keeps working when
+// the connection is replaced.
+func (i *InvokeGrpc) UIHandler(opts UIOptions) (http.Handler, error) {
+	return i.UIHandlerContext(context.Background(), opts)
+}
+
+// UIHandlerContext is like UIHandler, but the reflection lookups are bound
+// to ctx.
+func (i *InvokeGrpc) UIHandlerContext(ctx context.Context, opts UIOptions) (http.Handler, error) {
+	assetNames := map[string]string{}
+	for _, names := range []struct {
+		names       []string
+		requireFile bool
+	}{{opts.ExtraJS, true}, {opts.ExtraCSS, true}, {opts.Assets, false}} {
+		if err := checkAssetNames(assetNames, names.names, names.requireFile); err != nil {
+			return nil, err
+		}
+	}
+	assets, err := configureAssets(opts.Assets)
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []*desc.MethodDescriptor
+	var files []*desc.FileDescriptor
+	err = i.withReconnect(ctx, func(c *Client) (bool, error) {
+		source, done := c.lookupSource(ctx)
+		defer done()
+		err := withContext(ctx, func() error {
+			// getMethods consumes the configs, so every attempt gets its own
+			configs, err := ComputeSvcConfigs(opts.Services, opts.Methods)
+			if err != nil {
+				return err
+			}
+			if methods, err = getMethods(source, configs); err != nil {
+				return err
+			}
+			files, err = grpcurl.GetAllFiles(source)
+			return err
+		})
+		return errConnLost(err), lookupError(ctx, i.G.Host, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var handlerOpts []standalone.HandlerOption
+	if len(opts.DefaultMetadata) > 0 {
+		handlerOpts = append(handlerOpts, standalone.WithDefaultMetadata(opts.DefaultMetadata))
+	}
+	if len(opts.Metadata) > 0 {
+		handlerOpts = append(handlerOpts, standalone.WithMetadata(opts.Metadata))
+	}
+	if len(opts.PreserveHeaders) > 0 {
+		handlerOpts = append(handlerOpts, standalone.PreserveHeaders(opts.PreserveHeaders))
+	}
+	if opts.Verbosity > 0 {
+		handlerOpts = append(handlerOpts, standalone.WithInvokeVerbosity(opts.Verbosity))
+	}
+	handlerOpts = append(handlerOpts, configureJSandCSS(opts.ExtraJS, standalone.AddJSFile)...)
+	handlerOpts = append(handlerOpts, configureJSandCSS(opts.ExtraCSS, standalone.AddCSSFile)...)
+	handlerOpts = append(handlerOpts, assets...)
+
+	handler := standalone.Handler(uiChannel{i}, i.G.Host, methods, files, handlerOpts...)
+	if opts.Verbosity > 0 {
+		handler = logRequests(handler, opts.Verbosity)
+	}
+	if opts.BasePath != "" && opts.BasePath != "/" {
+		withoutSlash := strings.TrimSuffix(opts.BasePath, "/")
+		mux := http.NewServeMux()
+		// the mux redirects the bare path to the one with a trailing slash
+		mux.Handle(withoutSlash+"/", http.StripPrefix(withoutSlash, handler))
+		handler = mux
+	}
+	return handler, nil
+}
+
+// uiChannel invokes the RPCs of the UI on the client the registry holds for
+// G at the time.
+type uiChannel struct {
+	i *InvokeGrpc
+}
+
+func (ch uiChannel) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	c, release, err := ch.i.acquireClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return c.cc.Invoke(ctx, method, args, reply, opts...)
+}
+
+func (ch uiChannel) NewStream(ctx context.Context, sd *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	c, release, err := ch.i.acquireClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := c.cc.NewStream(ctx, sd, method, opts...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if ctx.Done() != nil {
+		go func() {
+			<-ctx.Done()
+			release()
+		}()
+	}
+	return releasingStream{stream, release}, nil
+}
+
+// releasingStream releases the client of a stream once the stream ends:
+// when receiving fails, io.EOF included, or when its context is done.
+type releasingStream struct {
+	grpc.ClientStream
+	release func()
+}
+
+func (s releasingStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.release()
+	}
+	return err
+}
+
+// logRequests logs every request to handler with the status code, duration
+// and size of its response.
+func logRequests(handler http.Handler, verbosity int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		if verbosity > 1 {
+			if req, err := httputil.DumpRequest(r, verbosity > 2); err != nil {
+				internal.LogErrorf("could not dump request: %v", err)
+			} else {
+				internal.LogInfof("received request:\n%s", string(req))
+			}
+		}
+
+		cs := codeSniffer{w: w}
+		handler.ServeHTTP(&cs, r)
+
+		millis := time.Since(start).Nanoseconds() / (1000 * 1000)
+		internal.LogInfof("%s %s %s %d %dms %dbytes", r.RemoteAddr, r.Method, r.RequestURI, cs.code, millis, cs.size)
+	})
+}
+
+// UICommand runs the ui command with the command-line arguments args,
+// serving the web UI of a host until it fails.
+func UICommand(args []string) {
+	fs := flag.NewFlagSet("ui", flag.ExitOnError)
+	host := fs.String("host", "", "The address of the gRPC server, e.g. 127.0.0.1:40061.")
+	addr := fs.String("addr", "127.0.0.1:8080", "The address the web UI listens on.")
+	basePath := fs.String("base-path", "/", "The path the web UI is served under.")
+	verbosity := fs.Int("v", 0, "Log requests when greater than zero, and dump them when greater than one.")
+	var svcs, mtds, extraJS, extraCSS, assets, defHeaders, rpcHeaders, prsvHeaders multiString
+	fs.Var(&svcs, "service", "A service to show in the UI, may be given multiple times. All are shown if neither -service nor -method is given.")
+	fs.Var(&mtds, "method", "A fully-qualified method to show in the UI, may be given multiple times.")
+	fs.Var(&extraJS, "extra-js", "A JavaScript file added to the page, may be given multiple times.")
+	fs.Var(&extraCSS, "extra-css", "A CSS file added to the page, may be given multiple times.")
+	fs.Var(&assets, "also-serve", "A file or folder served along with the page, may be given multiple times.")
+	fs.Var(&defHeaders, "default-header", `Metadata prefilled in the form, as "name: value", may be given multiple times.`)
+	fs.Var(&rpcHeaders, "rpc-header", `Metadata sent with every RPC, as "name: value", may be given multiple times.`)
+	fs.Var(&prsvHeaders, "preserve-header", "An HTTP header sent as metadata with every RPC, may be given multiple times.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s ui [flags] -host host:port\n\nServes the gRPC web UI for the services of host.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *host == "" {
+		fail(nil, "The -host flag is required.")
+	}
+
+	err := ServeUI(*host, *addr, UIOptions{
+		Services:        svcs,
+		Methods:         mtds,
+		ExtraJS:         extraJS,
+		ExtraCSS:        extraCSS,
+		Assets:          assets,
+		DefaultMetadata: defHeaders,
+		Metadata:        rpcHeaders,
+		PreserveHeaders: prsvHeaders,
+		Verbosity:       *verbosity,
+		BasePath:        *basePath,
+	})
+	fail(err, "Failed to serve web UI")
+}
@@ -0,0 +1,109 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/test-instructor/grpc-plugin/demo"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+)
+
+func TestUIHandler(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r)
+
+	dir := t.TempDir()
+	js := filepath.Join(dir, "custom.js")
+	if err := os.WriteFile(js, []byte("console.log('custom')"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h, err := ig.UIHandler(UIOptions{
+		Methods:  []string{"user.User.Login", "user.User.RegisterUser"},
+		ExtraJS:  []string{js},
+		BasePath: "/ui",
+		Metadata: []string{"id: 1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+	page := get("/ui/")
+	if page.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d", page.Code)
+	}
+	body := page.Body.String()
+	if !strings.Contains(body, "user.User.Login") || strings.Contains(body, "user.User.UserInfo") {
+		t.Fatal("expected the page to list the configured methods only")
+	}
+	if !strings.Contains(body, "custom.js") || !strings.Contains(get("/ui/s/custom.js").Body.String(), "custom") {
+		t.Fatal("expected the extra JS to be served")
+	}
+
+	invoke := func(body string) (*httptest.ResponseRecorder, RpcResult) {
+		req := httptest.NewRequest("POST", "/ui/invoke/user.User.RegisterUser", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "_grpcui_csrf_token", Value: "token"})
+		req.Header.Set("x-grpcui-csrf-token", "token")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var results RpcResult
+		json.Unmarshal(rec.Body.Bytes(), &results)
+		return rec, results
+	}
+	if rec, results := invoke(fmt.Sprintf(`{"data":[{"UserName":%q}]}`, uniqueName("ui"))); rec.Code != http.StatusOK || results.Error != nil || len(results.Responses) != 1 {
+		t.Fatalf("expected the RPC to succeed, got %d %s", rec.Code, rec.Body)
+	}
+
+	// the RPCs use the connection the registry holds at the time
+	r.CloseAll()
+	if rec, results := invoke(fmt.Sprintf(`{"data":[{"UserName":%q}]}`, uniqueName("ui"))); rec.Code != http.StatusOK || results.Error != nil {
+		t.Fatalf("expected the RPC to reconnect, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestUIHandlerServices(t *testing.T) {
+	s := demo.NewSvc()
+	testpb.RegisterTestServiceServer(s, testpb.UnimplementedTestServiceServer{})
+	addr := serveTestSvc(t, s)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+
+	// grpc.testing.TestService is listed before user.User
+	h, err := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r).UIHandler(UIOptions{Services: []string{"grpc.testing.TestService"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "grpc.testing.TestService.EmptyCall") || strings.Contains(body, "user.User.") {
+		t.Fatal("expected the page to list the configured service only")
+	}
+}
+
+func TestUIHandlerAssets(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r)
+
+	if _, err := ig.UIHandler(UIOptions{ExtraCSS: []string{filepath.Join(t.TempDir(), "missing.css")}}); err == nil {
+		t.Fatal("expected a missing file to be rejected")
+	}
+	if _, err := ig.UIHandler(UIOptions{Methods: []string{"user.User.Missing"}}); err == nil {
+		t.Fatal("expected an unknown method to be rejected")
+	}
+}
@@ -0,0 +1,477 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// ValidationMode selects how request bodies are checked against the request
+// type of the method before they are sent.
+type ValidationMode int
+
+const (
+	// ValidationStrict rejects bodies with any problem, including unknown
+	// fields.
+	ValidationStrict ValidationMode = iota
+	// ValidationLenient drops unknown fields, reporting each as a warning in
+	// RpcResult.Warnings, and rejects bodies with other problems.
+	ValidationLenient
+	// ValidationOff skips the check, bodies are only parsed when they are
+	// sent.
+	ValidationOff
+)
+
+// ValidationProblem is a problem of a request body at Path, a JSON path
+// such as $.users[0].name.
+type ValidationProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (p ValidationProblem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// ValidationError lists all problems found in a request body. Invoking
+// reports it wrapped in an Error of kind ErrBadInput.
+type ValidationError struct {
+	Problems []ValidationProblem
+}
+
+func (e *ValidationError) Error() string {
+	texts := make([]string, len(e.Problems))
+	for n, p := range e.Problems {
+		texts[n] = p.String()
+	}
+	return "invalid request: " + strings.Join(texts, "; ")
+}
+
+// ValidateMessage checks that data is a valid protojson form of a message of
+// type md: no unknown fields, values of the right JSON types, known enum
+// names, integers within the range of their type, bytes in base64, and at
+// most one field of each oneof. All problems are returned in a
+// ValidationError. In lenient mode unknown fields are not problems, but are
+// dropped from the returned body and reported as warnings instead. data is
+// returned unchanged in ValidationOff mode and when nothing was dropped.
+func ValidateMessage(md *desc.MessageDescriptor, data json.RawMessage, mode ValidationMode) (json.RawMessage, []ValidationProblem, error) {
+	if mode == ValidationOff {
+		return data, nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, nil, &ValidationError{Problems: []ValidationProblem{{Path: "$", Message: fmt.Sprintf("invalid JSON: %v", err)}}}
+	}
+	if dec.More() {
+		return nil, nil, &ValidationError{Problems: []ValidationProblem{{Path: "$", Message: "unexpected data after the message"}}}
+	}
+
+	vr := &validator{mode: mode}
+	v = vr.message(md, "$", v)
+	if len(vr.problems) > 0 {
+		return nil, vr.warnings, &ValidationError{Problems: vr.problems}
+	}
+	if !vr.dropped {
+		return data, vr.warnings, nil
+	}
+	cleaned, err := json.Marshal(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cleaned, vr.warnings, nil
+}
+
+type validator struct {
+	mode     ValidationMode
+	problems []ValidationProblem
+	warnings []ValidationProblem
+	dropped  bool
+}
+
+func (vr *validator) problem(path, format string, args ...interface{}) {
+	vr.problems = append(vr.problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// message checks v at path against md and returns it, without the unknown
+// fields dropped in lenient mode.
+func (vr *validator) message(md *desc.MessageDescriptor, path string, v interface{}) interface{} {
+	if v == nil {
+		return v
+	}
+	if vr.wellKnown(md, path, v) {
+		return v
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		vr.problem(path, "expected an object of %s, got %s", md.GetFullyQualifiedName(), jsonType(v))
+		return v
+	}
+
+	setOneOfs := map[*desc.OneOfDescriptor]string{}
+	for _, name := range sortedKeys(obj) {
+		fieldPath := path + "." + name
+		fd := findJSONField(md, name)
+		if fd == nil {
+			if vr.mode == ValidationLenient {
+				vr.warnings = append(vr.warnings, ValidationProblem{Path: fieldPath, Message: "unknown field of " + md.GetFullyQualifiedName() + " dropped"})
+				delete(obj, name)
+				vr.dropped = true
+				continue
+			}
+			vr.problem(fieldPath, "unknown field of %s", md.GetFullyQualifiedName())
+			continue
+		}
+		if ood := fd.GetOneOf(); ood != nil && !ood.IsSynthetic() && obj[name] != nil {
+			if other, ok := setOneOfs[ood]; ok {
+				vr.problem(fieldPath, "oneof %s is already set by %s", ood.GetName(), other)
+			} else {
+				setOneOfs[ood] = name
+			}
+		}
+		obj[name] = vr.field(fd, fieldPath, obj[name])
+	}
+	return obj
+}
+
+// findJSONField finds the field of md named name in JSON, either by its
+// JSON name or by its name in the proto file, as jsonpb accepts both.
+func findJSONField(md *desc.MessageDescriptor, name string) *desc.FieldDescriptor {
+	for _, fd := range md.GetFields() {
+		if fd.GetJSONName() == name || fd.GetName() == name {
+			return fd
+		}
+	}
+	return nil
+}
+
+func (vr *validator) field(fd *desc.FieldDescriptor, path string, v interface{}) interface{} {
+	if v == nil {
+		return v
+	}
+	switch {
+	case fd.IsMap():
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			vr.problem(path, "expected an object, got %s", jsonType(v))
+			return v
+		}
+		for _, key := range sortedKeys(obj) {
+			entryPath := path + "[" + strconv.Quote(key) + "]"
+			vr.mapKey(fd.GetMapKeyType(), entryPath, key)
+			obj[key] = vr.singular(fd.GetMapValueType(), entryPath, obj[key])
+		}
+		return obj
+	case fd.IsRepeated():
+		values, ok := v.([]interface{})
+		if !ok {
+			vr.problem(path, "expected an array, got %s", jsonType(v))
+			return v
+		}
+		for n := range values {
+			values[n] = vr.singular(fd, fmt.Sprintf("%s[%d]", path, n), values[n])
+		}
+		return values
+	default:
+		return vr.singular(fd, path, v)
+	}
+}
+
+func (vr *validator) singular(fd *desc.FieldDescriptor, path string, v interface{}) interface{} {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return vr.message(fd.GetMessageType(), path, v)
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		vr.enum(fd.GetEnumType(), path, v)
+	default:
+		vr.scalar(fd.GetType(), path, v)
+	}
+	return v
+}
+
+// mapKey checks a map key, which is always a string in JSON.
+func (vr *validator) mapKey(fd *desc.FieldDescriptor, path, key string) {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		if key != "true" && key != "false" {
+			vr.problem(path, "expected a bool key, got %q", key)
+		}
+	default:
+		vr.scalar(fd.GetType(), path, json.Number(key))
+	}
+}
+
+func (vr *validator) enum(ed *desc.EnumDescriptor, path string, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+	case string:
+		if ed.FindValueByName(v) == nil {
+			var names []string
+			for _, evd := range ed.GetValues() {
+				names = append(names, evd.GetName())
+			}
+			vr.problem(path, "unknown value %q of enum %s, expected one of %s", v, ed.GetFullyQualifiedName(), strings.Join(names, ", "))
+		}
+	case json.Number:
+		// unknown numbers are kept as they are
+		vr.integer(path, v, math.MinInt32, math.MaxInt32, "int32")
+	default:
+		vr.problem(path, "expected a name of enum %s, got %s", ed.GetFullyQualifiedName(), jsonType(v))
+	}
+}
+
+func (vr *validator) scalar(t descriptor.FieldDescriptorProto_Type, path string, v interface{}) {
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		if _, ok := v.(string); !ok {
+			vr.problem(path, "expected a string, got %s", jsonType(v))
+		}
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		s, ok := v.(string)
+		if !ok {
+			vr.problem(path, "expected a base64 string, got %s", jsonType(v))
+		} else if !isBase64(s) {
+			vr.problem(path, "invalid base64 string")
+		}
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		if _, ok := v.(bool); !ok {
+			vr.problem(path, "expected a bool, got %s", jsonType(v))
+		}
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT, descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		switch v := v.(type) {
+		case json.Number:
+		case string:
+			if _, err := strconv.ParseFloat(v, 64); err != nil && v != "NaN" && v != "Infinity" && v != "-Infinity" {
+				vr.problem(path, "expected a number, got %q", v)
+			}
+		default:
+			vr.problem(path, "expected a number, got %s", jsonType(v))
+		}
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32, descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		vr.number(path, v, math.MinInt32, math.MaxInt32, "int32")
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		vr.number(path, v, 0, math.MaxUint32, "uint32")
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		vr.number(path, v, math.MinInt64, math.MaxInt64, "int64")
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		switch v := v.(type) {
+		case json.Number:
+			vr.unsigned(path, string(v))
+		case string:
+			vr.unsigned(path, v)
+		default:
+			vr.problem(path, "expected an integer, got %s", jsonType(v))
+		}
+	}
+}
+
+// number checks an integer, which may be quoted in JSON.
+func (vr *validator) number(path string, v interface{}, min, max int64, typ string) {
+	switch v := v.(type) {
+	case json.Number:
+		vr.integer(path, v, min, max, typ)
+	case string:
+		vr.integer(path, json.Number(v), min, max, typ)
+	default:
+		vr.problem(path, "expected an integer, got %s", jsonType(v))
+	}
+}
+
+func (vr *validator) integer(path string, n json.Number, min, max int64, typ string) {
+	i, err := strconv.ParseInt(string(n), 10, 64)
+	if err != nil {
+		// max+1 is exact as a float64 where max may not be, e.g. for int64
+		vr.integral(path, string(n), float64(min), float64(max)+1, typ)
+		return
+	}
+	if i < min || i > max {
+		vr.problem(path, "%s out of range of %s", string(n), typ)
+	}
+}
+
+func (vr *validator) unsigned(path, s string) {
+	if _, err := strconv.ParseUint(s, 10, 64); err != nil {
+		vr.integral(path, s, 0, float64(1<<64), "uint64")
+	}
+}
+
+// integral checks an integer written with an exponent or a fraction, such
+// as 1e3 or 1.0, which protojson accepts, to be in [lo, hi).
+func (vr *validator) integral(path, s string, lo, hi float64, typ string) {
+	f, err := strconv.ParseFloat(s, 64)
+	switch {
+	case err != nil && !errors.Is(err, strconv.ErrRange):
+		vr.problem(path, "expected an integer, got %q", s)
+	case f != math.Trunc(f):
+		vr.problem(path, "expected an integer, got %s", s)
+	case f < lo || f >= hi:
+		vr.problem(path, "%s out of range of %s", s, typ)
+	}
+}
+
+// wellKnown checks the well-known types with special JSON forms, reporting
+// whether md is one of them.
+func (vr *validator) wellKnown(md *desc.MessageDescriptor, path string, v interface{}) bool {
+	name := md.GetFullyQualifiedName()
+	if t, ok := wrapperTypes[name]; ok {
+		vr.scalar(t, path, v)
+		return true
+	}
+	switch name {
+	case "google.protobuf.Timestamp":
+		s, ok := v.(string)
+		if !ok {
+			vr.problem(path, "expected an RFC 3339 timestamp, got %s", jsonType(v))
+		} else if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			vr.problem(path, "invalid RFC 3339 timestamp %q", s)
+		}
+	case "google.protobuf.Duration":
+		s, ok := v.(string)
+		if !ok {
+			vr.problem(path, "expected a duration such as \"1.5s\", got %s", jsonType(v))
+		} else if _, err := strconv.ParseFloat(strings.TrimSuffix(s, "s"), 64); err != nil || !strings.HasSuffix(s, "s") {
+			vr.problem(path, "invalid duration %q, expected seconds such as \"1.5s\"", s)
+		}
+	case "google.protobuf.FieldMask":
+		if _, ok := v.(string); !ok {
+			vr.problem(path, "expected a comma-separated list of paths, got %s", jsonType(v))
+		}
+	case "google.protobuf.Struct":
+		if _, ok := v.(map[string]interface{}); !ok {
+			vr.problem(path, "expected an object, got %s", jsonType(v))
+		}
+	case "google.protobuf.ListValue":
+		if _, ok := v.([]interface{}); !ok {
+			vr.problem(path, "expected an array, got %s", jsonType(v))
+		}
+	case "google.protobuf.Value":
+		// any JSON value
+	case "google.protobuf.Any":
+		// the fields depend on @type, which may be unknown here
+		if obj, ok := v.(map[string]interface{}); !ok {
+			vr.problem(path, "expected an object, got %s", jsonType(v))
+		} else if _, ok := obj["@type"].(string); !ok {
+			vr.problem(path+".@type", "expected the type URL of the message")
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isBase64(s string) bool {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if _, err := enc.DecodeString(s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a bool"
+	case json.Number:
+		return "a number"
+	case string:
+		return "a string"
+	case []interface{}:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// requestValidator validates the requests of one call, collecting the
+// warnings of all of them.
+type requestValidator struct {
+	md   *desc.MessageDescriptor
+	mode ValidationMode
+
+	mu       sync.Mutex
+	warnings []ValidationProblem
+}
+
+// validate validates a request, the nth of a stream, or the only one if n
+// is negative.
+func (rv *requestValidator) validate(data json.RawMessage, n int) (json.RawMessage, error) {
+	cleaned, warnings, err := ValidateMessage(rv.md, data, rv.mode)
+	if n >= 0 {
+		// tell the messages of a stream apart
+		prefix := fmt.Sprintf("$[%d]", n)
+		for k := range warnings {
+			warnings[k].Path = prefix + strings.TrimPrefix(warnings[k].Path, "$")
+		}
+		if ve, ok := err.(*ValidationError); ok {
+			for k := range ve.Problems {
+				ve.Problems[k].Path = prefix + strings.TrimPrefix(ve.Problems[k].Path, "$")
+			}
+		}
+	}
+	rv.mu.Lock()
+	rv.warnings = append(rv.warnings, warnings...)
+	rv.mu.Unlock()
+	return cleaned, err
+}
+
+// prepare validates the requests of input right away, and those of a
+// stream as they are sent.
+func (rv *requestValidator) prepare(input *rpcInput, hooks *invokeHooks) error {
+	if hooks.next != nil {
+		hooks.next = rv.source(hooks.next)
+		return nil
+	}
+	for n, data := range input.Data {
+		cleaned, err := rv.validate(data, -1)
+		if err != nil {
+			return err
+		}
+		input.Data[n] = cleaned
+	}
+	return nil
+}
+
+// source validates the messages supplied by next as they are sent.
+func (rv *requestValidator) source(next func() (json.RawMessage, error)) func() (json.RawMessage, error) {
+	n := 0
+	return func() (json.RawMessage, error) {
+		msg, err := next()
+		if err != nil {
+			return nil, err
+		}
+		msg, err = rv.validate(msg, n)
+		n++
+		return msg, err
+	}
+}
+
+func (rv *requestValidator) result() []ValidationProblem {
+	rv.mu.Lock()
+	defer rv.mu.Unlock()
+	return rv.warnings
+}
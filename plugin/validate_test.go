@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateMessage(t *testing.T) {
+	md := parseSchemaProto(t).FindMessage("schema.Node")
+	tests := []struct {
+		name string
+		body string
+		want []ValidationProblem
+	}{
+		{"valid", `{"id":"12","weight":3,"data":"aGk=","color":"RED","children":[{"old_name":"x"}],"labels":{"1":"a"},"text":"t",
+			"flag":null,"created":"2023-01-01T00:00:00Z","ttl":"1.5s","attrs":{"a":[1]},"count":"7","detail":{"@type":"type.googleapis.com/schema.Node"}}`, nil},
+		{"unknown field", `{"children":[{"missing":1}]}`, []ValidationProblem{
+			{Path: "$.children[0].missing", Message: "unknown field of schema.Node"},
+		}},
+		{"wrong types", `{"id":true,"weight":"x","data":1,"children":{},"text":2,"flag":"yes"}`, []ValidationProblem{
+			{Path: "$.children", Message: "expected an array, got an object"},
+			{Path: "$.data", Message: "expected a base64 string, got a number"},
+			{Path: "$.flag", Message: "expected a bool, got a string"},
+			{Path: "$.id", Message: "expected an integer, got a bool"},
+			{Path: "$.text", Message: "expected a string, got a number"},
+			{Path: "$.weight", Message: `expected an integer, got "x"`},
+		}},
+		{"enum", `{"color":"BLUE"}`, []ValidationProblem{
+			{Path: "$.color", Message: `unknown value "BLUE" of enum schema.Color, expected one of COLOR_UNSPECIFIED, RED`},
+		}},
+		{"overflow", `{"id":"9223372036854775808","weight":-1,"count":1e30,"labels":{"2147483648":"a"}}`, []ValidationProblem{
+			{Path: "$.count", Message: "1e30 out of range of int64"},
+			{Path: "$.id", Message: "9223372036854775808 out of range of int64"},
+			{Path: `$.labels["2147483648"]`, Message: "2147483648 out of range of int32"},
+			{Path: "$.weight", Message: "-1 out of range of uint32"},
+		}},
+		{"exponents and fractions", `{"id":"1e3","weight":1e3,"count":1.0,"labels":{"1":"a"}}`, nil},
+		{"fractions", `{"id":"1.5","weight":2.5e-1,"count":"1e3"}`, []ValidationProblem{
+			{Path: "$.id", Message: "expected an integer, got 1.5"},
+			{Path: "$.weight", Message: "expected an integer, got 2.5e-1"},
+		}},
+		{"exponent overflow", `{"id":"1e19","weight":4294967296.0,"count":-1e19}`, []ValidationProblem{
+			{Path: "$.count", Message: "-1e19 out of range of int64"},
+			{Path: "$.id", Message: "1e19 out of range of int64"},
+			{Path: "$.weight", Message: "4294967296.0 out of range of uint32"},
+		}},
+		{"base64", `{"data":"not base64!"}`, []ValidationProblem{
+			{Path: "$.data", Message: "invalid base64 string"},
+		}},
+		{"oneof", `{"number":1,"text":"t"}`, []ValidationProblem{
+			{Path: "$.text", Message: "oneof value is already set by number"},
+		}},
+		{"well-known types", `{"created":"yesterday","ttl":2,"detail":{}}`, []ValidationProblem{
+			{Path: "$.created", Message: `invalid RFC 3339 timestamp "yesterday"`},
+			{Path: "$.detail.@type", Message: "expected the type URL of the message"},
+			{Path: "$.ttl", Message: `expected a duration such as "1.5s", got a number`},
+		}},
+		{"not an object", `[]`, []ValidationProblem{
+			{Path: "$", Message: "expected an object of schema.Node, got an array"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, warnings, err := ValidateMessage(md, json.RawMessage(tt.body), ValidationStrict)
+			if len(warnings) != 0 {
+				t.Fatalf("unexpected warnings %v", warnings)
+			}
+			if tt.want == nil {
+				if err != nil || string(out) != tt.body {
+					t.Fatalf("expected the body to be valid, got %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected a ValidationError, got %v", err)
+			}
+			if !reflect.DeepEqual(ve.Problems, tt.want) {
+				t.Fatalf("expected problems\n%v\ngot\n%v", tt.want, ve.Problems)
+			}
+		})
+	}
+}
+
+func TestValidateMessageLenient(t *testing.T) {
+	md := parseSchemaProto(t).FindMessage("schema.Node")
+	out, warnings, err := ValidateMessage(md, json.RawMessage(`{"id":1,"extra":{"a":1},"children":[{"text":"t","more":true}]}`), ValidationLenient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != `{"children":[{"text":"t"}],"id":1}` {
+		t.Fatalf("expected the unknown fields to be dropped, got %s", out)
+	}
+	want := []ValidationProblem{
+		{Path: "$.children[0].more", Message: "unknown field of schema.Node dropped"},
+		{Path: "$.extra", Message: "unknown field of schema.Node dropped"},
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+
+	if _, _, err := ValidateMessage(md, json.RawMessage(`{"extra":1,"id":"x"}`), ValidationLenient); err == nil {
+		t.Fatal("expected other problems to be rejected")
+	}
+	if out, _, err := ValidateMessage(md, json.RawMessage(`{"extra":1}`), ValidationOff); err != nil || string(out) != `{"extra":1}` {
+		t.Fatalf("expected the body to pass unchecked, got %s %v", out, err)
+	}
+}
+
+func TestInvokeValidation(t *testing.T) {
+	addr := startTestSvc(t)
+	r := NewRegistry(0, 0)
+	defer r.CloseAll()
+	ig := NewInvokeGrpcWithRegistry(&Grpc{Host: addr}, r)
+	ctx := context.Background()
+
+	body := `{"UserName":"u","Sex":"Other","Unknown":1}`
+	_, err := ig.Call(ctx, Request{Method: "user.User.RegisterUser", Body: strings.NewReader(body)})
+	var ve *ValidationError
+	if !errors.Is(err, ErrBadInput) || !errors.As(err, &ve) || len(ve.Problems) != 2 {
+		t.Fatalf("expected both problems to be reported, got %v", err)
+	}
+
+	body = fmt.Sprintf(`{"UserName":%q,"Unknown":1}`, uniqueName("validate"))
+	results, err := ig.Call(ctx, Request{Method: "user.User.RegisterUser", Body: strings.NewReader(body), Validation: ValidationLenient})
+	if err != nil || results.Error != nil {
+		t.Fatalf("expected the unknown field to be dropped: %v %+v", err, results)
+	}
+	if len(results.Warnings) != 1 || results.Warnings[0].Path != "$.Unknown" {
+		t.Fatalf("unexpected warnings %v", results.Warnings)
+	}
+
+	stream := `[{"UserName":"a"},{"UserName":"b","msg":{"arrays":["x"]}}]`
+	_, err = ig.Call(ctx, Request{Method: "user.User.RegisterUsers", Body: strings.NewReader(stream)})
+	if !errors.As(err, &ve) || ve.Problems[0].Path != "$[1].msg.arrays[0]" {
+		t.Fatalf("expected the second message to be rejected, got %v", err)
+	}
+}